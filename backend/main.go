@@ -1,7 +1,12 @@
 package main
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/export"
+	"alphalabz/pkg/ldapauth"
+	"alphalabz/pkg/oidc"
+	"alphalabz/pkg/passkey"
 	"alphalabz/pkg/pocketbase"
 	"alphalabz/pkg/routes/labbook"
 	"alphalabz/pkg/routes/login"
@@ -10,6 +15,7 @@ import (
 	"alphalabz/pkg/settings"
 	"alphalabz/pkg/smtp"
 	"alphalabz/pkg/tools"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -25,6 +31,14 @@ import (
 var pbClient *pocketbase.PocketBaseClient
 var casbinEnforcer *casbin.CasbinEnforcer
 var SMTPClient *smtp.SMTPClient
+var revocationList = auth.NewRevocationList()
+var loginGuard = auth.NewLoginGuard()
+var oidcProvider *oidc.Provider
+var directory *ldapauth.Authenticator
+var sessionRegistry *auth.SessionRegistry
+var passkeys *passkey.Service
+var exporter *export.Exporter
+var permissionCatalog *casbin.Catalog
 
 func main() {
 	// Initialize settings from YAML file
@@ -55,18 +69,72 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize PocketBase client: %v", err)
 	}
+	sessionRegistry = auth.NewSessionRegistry(pbClient)
+	exporter = export.NewExporter(pbClient, "./uploads/export")
 
 	// Initialize Casbin with policies
-	policies, err := casbin.FetchPermissions(pbClient)
+	policies, groupingPolicies, err := casbin.FetchPermissions(pbClient)
 	if err != nil {
 		log.Fatalf("Failed to fetch policies: %v", err)
 	}
 
-	casbinEnforcer, err = casbin.InitializeCasbin(policies)
+	casbinEnforcer, err = casbin.InitializeCasbin(policies, groupingPolicies)
 	if err != nil {
 		log.Fatalf("Failed to initialize Casbin: %v", err)
 	}
 
+	// Apply role changes made in PocketBase (e.g. in the admin UI) as they happen
+	casbinEnforcer.WatchRoles(context.Background(), pbClient)
+
+	// Initialize OpenID Connect provider. Password login keeps working if the IdP is unreachable.
+	if settings.OIDC.Enabled {
+		oidcProvider, err = oidc.NewProvider(
+			settings.OIDC.Issuer,
+			settings.OIDC.ClientId,
+			settings.OIDC.ClientSecret,
+			settings.OIDC.RedirectUrl,
+			settings.OIDC.Scopes,
+			nil,
+		)
+		if err != nil {
+			log.Printf("Failed to initialize OIDC provider: %v", err)
+		} else {
+			log.Println("OIDC provider initialized successfully")
+		}
+	}
+
+	// Initialize LDAP authentication, local password login is used when disabled
+	if settings.LDAP.Enabled {
+		directory = ldapauth.NewAuthenticator(ldapauth.Config{
+			Url:                settings.LDAP.Url,
+			StartTLS:           settings.LDAP.StartTLS,
+			InsecureSkipVerify: settings.LDAP.InsecureSkipVerify,
+			BindDN:             settings.LDAP.BindDN,
+			BindPassword:       settings.LDAP.BindPassword,
+			BaseDN:             settings.LDAP.BaseDN,
+			UserFilter:         settings.LDAP.UserFilter,
+			EmailAttribute:     settings.LDAP.EmailAttribute,
+			NameAttribute:      settings.LDAP.NameAttribute,
+			GroupAttribute:     settings.LDAP.GroupAttribute,
+		})
+		log.Println("LDAP authentication enabled")
+	}
+
+	// Initialize passkey (WebAuthn) login
+	if settings.WebAuthn.Enabled {
+		passkeys, err = passkey.NewService(
+			settings.WebAuthn.RPID,
+			settings.WebAuthn.RPDisplayName,
+			settings.WebAuthn.RPOrigins,
+			passkey.NewPocketBaseStore(pbClient),
+		)
+		if err != nil {
+			log.Printf("Failed to initialize passkey login: %v", err)
+		} else {
+			log.Println("Passkey login enabled")
+		}
+	}
+
 	// Create uploads directory if it doesn't exist
 	if err = tools.CreateUploadsDir(); err != nil {
 		log.Fatal("Failed to create uploads directory")
@@ -79,21 +147,40 @@ func main() {
 
 	// Setup and start server
 	r := setupRouter()
+
+	// Build the permission catalog role documents are checked against, from the permissions the routes check
+	permissionCatalog, err = casbin.LoadCatalog("./defaultPermission.json")
+	if err != nil {
+		log.Fatalf("Failed to load permission catalog: %v", err)
+	}
+
 	port := settings.Server.Port
 	log.Printf("Server starting on port %s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, r))
 }
 
 // JWTAuthMiddleware will check the JWT token and validate it.
-// If valid, it will pass the request to the next handler. Otherwise, it will return a 401 Unauthorized response.
+// The token signature is verified by PocketBase, so forged or foreign tokens are rejected.
+// If valid, the caller is resolved into an auth.Principal stored in the request context and the request is passed to the next handler.
+// Otherwise, it will return a 401 Unauthorized response.
 func jwtExpirationMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var jwtSkipPaths = map[string]bool{
-			"/health":        true,
-			"/login/account": true,
-			// "/login/oauth":   true,
+			"/health":               true,
+			"/login/account":        true,
+			"/login/oauth":          true,
+			"/login/oauth/callback": true,
+			"/login/mfa":            true,
+			"/login/mfa/enroll":     true,
+			"/login/passkey/begin":  true,
+			"/login/passkey/finish": true,
 			// "/login/sso":     true,
-			"/users/signup": true,
+			"/user/signup":                         true,
+			"/user/register":                       true,
+			"/user/account/password/reset":         true,
+			"/user/account/password/reset/confirm": true,
+			"/user/account/modify/email/confirm":   true,
+			"/user/account/verify/confirm":         true,
 		}
 
 		// Check if the path is in the skip list. If it is, then skip JWT validation and pass the request to the next handler.
@@ -108,29 +195,64 @@ func jwtExpirationMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Verify JWT expiration. If the token has expired or is invalid, return a 401 Unauthorized response.
-		valid, err := tools.VerifyJWTExpiration(rawToken)
-		if err != nil || !valid {
+		// Reject tokens that were signed out through /login/logout
+		if revocationList.IsRevoked(rawToken) {
+			http.Error(w, "token revoked", http.StatusUnauthorized)
+			return
+		}
+
+		// Personal access tokens are looked up by hash, everything else must be a PocketBase JWT.
+		// Verify JWT signature, claims and expiration, then load the caller.
+		// If the token is forged, expired or invalid, return a 401 Unauthorized response.
+		// Login tokens must also belong to a session that was not ended.
+		var principal *auth.Principal
+		var session pocketbase.Session
+		if auth.IsAPIToken(rawToken) {
+			principal, err = auth.ResolveAPIToken(pbClient, rawToken)
+		} else {
+			var sessionErr error
+			session, sessionErr = sessionRegistry.Lookup(rawToken)
+			if sessionErr != nil || session.Id == "" {
+				http.Error(w, "session ended or unknown", http.StatusUnauthorized)
+				return
+			}
+			principal, err = auth.ResolvePrincipal(pbClient, rawToken)
+		}
+		if err != nil {
 			http.Error(w, "token expired or invalid", http.StatusUnauthorized)
 			return
 		}
 
+		// Requests of an admin impersonating a user are read-only and audited
+		if session.ImpersonatorId != "" {
+			principal.ImpersonatorId = session.ImpersonatorId
+			auth.ServeImpersonated(pbClient, principal, next, w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+			return
+		}
+
 		// Token is valid. Pass the request to the next handler.
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
 	})
 }
 
 func initCron(pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, adminEmail, adminPassword string) *cron.Cron {
 	cronHandler := cron.New()
 
+	// Roles are reloaded as they change, the full reload only catches changes that were missed
 	cronHandler.AddFunc("@every 4h", func() {
-		ce.ReloadPolicies(pbClient)
+		if err := ce.ReloadPolicies(pbClient); err != nil {
+			log.Println("Failed to reload policies:", err)
+		}
 	})
 
 	cronHandler.AddFunc("@every 1d", func() {
 		tools.CleanUploads()
 	})
 
+	cronHandler.AddFunc("@every 1h", func() {
+		sessionRegistry.PurgeExpired()
+	})
+
 	cronHandler.AddFunc("@every 30d", func() {
 		pbClient.SuperTokenRenew(adminEmail, adminPassword)
 	})
@@ -169,12 +291,40 @@ func setupRouter() *chi.Mux {
 	// Login to system
 	r.Route("/login", func(r chi.Router) {
 		r.Post("/account", func(w http.ResponseWriter, r *http.Request) {
-			login.HandleAccountLogin(w, r, pbClient)
+			login.HandleAccountLogin(w, r, pbClient, directory, loginGuard, SMTPClient, sessionRegistry)
 		})
 
-		// r.Post("/oauth", func(w http.ResponseWriter, r *http.Request) {
-		// 	// routes.HandleOAuth(w, r, pbClient)
-		// })
+		r.Post("/refresh", func(w http.ResponseWriter, r *http.Request) {
+			login.HandleTokenRefresh(w, r, pbClient, revocationList, sessionRegistry)
+		})
+
+		r.Post("/logout", func(w http.ResponseWriter, r *http.Request) {
+			login.HandleLogout(w, r, revocationList, sessionRegistry)
+		})
+
+		r.Post("/mfa", func(w http.ResponseWriter, r *http.Request) {
+			login.HandleMFAVerify(w, r, pbClient, loginGuard, SMTPClient, sessionRegistry)
+		})
+
+		r.Post("/mfa/enroll", func(w http.ResponseWriter, r *http.Request) {
+			login.HandleMFAEnroll(w, r, pbClient)
+		})
+
+		r.Post("/passkey/begin", func(w http.ResponseWriter, r *http.Request) {
+			login.HandlePasskeyLoginBegin(w, r, passkeys)
+		})
+
+		r.Post("/passkey/finish", func(w http.ResponseWriter, r *http.Request) {
+			login.HandlePasskeyLoginFinish(w, r, pbClient, passkeys, sessionRegistry)
+		})
+
+		r.Get("/oauth", func(w http.ResponseWriter, r *http.Request) {
+			login.HandleOAuthLogin(w, r, oidcProvider)
+		})
+
+		r.Get("/oauth/callback", func(w http.ResponseWriter, r *http.Request) {
+			login.HandleOAuthCallback(w, r, pbClient, oidcProvider, sessionRegistry)
+		})
 
 		// r.Post("/sso", func(w http.ResponseWriter, r *http.Request) {
 		// 	// routes.HandleSSO(w, r, pbClient)
@@ -183,42 +333,226 @@ func setupRouter() *chi.Mux {
 
 	// Users route
 	r.Route("/user", func(r chi.Router) {
-		r.Get("/view/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequireRecordPermission(casbinEnforcer, pbClient, "users", "view", "id", auth.UserRecord)).Get("/view/{id}", func(w http.ResponseWriter, r *http.Request) {
 			userId := chi.URLParam(r, "id")
 			user.HandleUserView(w, r, userId, pbClient, casbinEnforcer)
 		})
 
+		// The user list returns the fields named by the list scopes
+		casbin.RegisterPermission("users", "list:id,email,name,avatar,gender,role,user_settings,birthdate,status,verified,created,updated")
+
 		r.Get("/list", func(w http.ResponseWriter, r *http.Request) {
 			user.HandleUserList(w, r, pbClient, casbinEnforcer)
 		})
 
-		r.Post("/invite", func(w http.ResponseWriter, r *http.Request) {
-			user.HandleInviteNewUser(w, r, pbClient, casbinEnforcer, SMTPClient)
+		// Invitations, bulk invitations and sign-up approvals check the roles users are created in
+		casbin.RegisterPermission("users", "create:"+casbin.RoleIdScope)
+
+		r.Route("/invite", func(r chi.Router) {
+			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleInviteNewUser(w, r, pbClient, casbinEnforcer, SMTPClient)
+			})
+
+			r.Post("/bulk", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleBulkInvite(w, r, pbClient, casbinEnforcer, SMTPClient)
+			})
+
+			r.Get("/list", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleListInvitations(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.Post("/{id}/resend", func(w http.ResponseWriter, r *http.Request) {
+				invitationId := chi.URLParam(r, "id")
+				user.HandleResendInvitation(w, r, invitationId, pbClient, casbinEnforcer, SMTPClient)
+			})
+
+			r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				invitationId := chi.URLParam(r, "id")
+				user.HandleRevokeInvitation(w, r, invitationId, pbClient, casbinEnforcer)
+			})
 		})
 
 		r.Post("/signup", func(w http.ResponseWriter, r *http.Request) {
 			user.HandleSignUp(w, r, pbClient, casbinEnforcer)
 		})
 
-		r.Delete("/remove", func(w http.ResponseWriter, r *http.Request) {
-			user.HandleUserRemove(w, r, pbClient, casbinEnforcer)
+		r.Post("/register", func(w http.ResponseWriter, r *http.Request) {
+			user.HandleRegister(w, r, pbClient, SMTPClient)
+		})
+
+		r.Route("/pending", func(r chi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleListPendingUsers(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.Post("/{id}/approve", func(w http.ResponseWriter, r *http.Request) {
+				userId := chi.URLParam(r, "id")
+				user.HandleApproveUser(w, r, userId, pbClient, casbinEnforcer, SMTPClient)
+			})
+
+			r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				userId := chi.URLParam(r, "id")
+				user.HandleRejectUser(w, r, userId, pbClient, casbinEnforcer, SMTPClient)
+			})
+		})
+
+		r.With(auth.RequirePermission(casbinEnforcer, "users", "delete", "*")).Delete("/remove", func(w http.ResponseWriter, r *http.Request) {
+			user.HandleUserRemove(w, r, pbClient, casbinEnforcer, sessionRegistry)
+		})
+
+		r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "*")).Post("/{id}/suspend", func(w http.ResponseWriter, r *http.Request) {
+			userId := chi.URLParam(r, "id")
+			user.HandleSuspendUser(w, r, userId, pbClient, casbinEnforcer, sessionRegistry)
+		})
+
+		r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "*")).Post("/{id}/reactivate", func(w http.ResponseWriter, r *http.Request) {
+			userId := chi.URLParam(r, "id")
+			user.HandleReactivateUser(w, r, userId, pbClient, casbinEnforcer)
+		})
+
+		// The role change checks the role the user is moved to
+		casbin.RegisterPermission("users", "update:"+casbin.RoleIdScope)
+
+		r.Patch("/{id}/role", func(w http.ResponseWriter, r *http.Request) {
+			userId := chi.URLParam(r, "id")
+			user.HandleChangeUserRole(w, r, userId, pbClient, casbinEnforcer)
+		})
+
+		r.Route("/export", func(r chi.Router) {
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "view", "own")).Post("/", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleRequestExport(w, r, pbClient, casbinEnforcer, exporter)
+			})
+
+			r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				exportId := chi.URLParam(r, "id")
+				user.HandleExportStatus(w, r, exportId, exporter)
+			})
+
+			r.Get("/{id}/download", func(w http.ResponseWriter, r *http.Request) {
+				exportId := chi.URLParam(r, "id")
+				user.HandleDownloadExport(w, r, exportId, exporter)
+			})
 		})
 
-		r.Patch("/settings", func(w http.ResponseWriter, r *http.Request) {
+		r.Route("/impersonate", func(r chi.Router) {
+			r.Post("/stop", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleStopImpersonation(w, r, pbClient, sessionRegistry)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "impersonate", "*")).Post("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				userId := chi.URLParam(r, "id")
+				user.HandleStartImpersonation(w, r, userId, pbClient, casbinEnforcer, sessionRegistry)
+			})
+		})
+
+		r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "*")).Post("/unlock", func(w http.ResponseWriter, r *http.Request) {
+			user.HandleUnlockLogin(w, r, pbClient, casbinEnforcer, loginGuard)
+		})
+
+		r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Patch("/settings", func(w http.ResponseWriter, r *http.Request) {
 			user.HandlUpdateSettings(w, r, pbClient, casbinEnforcer)
 		})
 
+		r.Route("/sessions", func(r chi.Router) {
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "view", "own")).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleListSessions(w, r, pbClient, casbinEnforcer, sessionRegistry)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				sessionId := chi.URLParam(r, "id")
+				user.HandleEndSession(w, r, sessionId, pbClient, casbinEnforcer, sessionRegistry)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "*")).Delete("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+				userId := chi.URLParam(r, "id")
+				user.HandleEndUserSessions(w, r, userId, pbClient, casbinEnforcer, sessionRegistry)
+			})
+		})
+
+		r.Route("/tokens", func(r chi.Router) {
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "view", "own")).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleListAPITokens(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Post("/", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleCreateAPIToken(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				tokenId := chi.URLParam(r, "id")
+				user.HandleRevokeAPIToken(w, r, tokenId, pbClient, casbinEnforcer)
+			})
+		})
+
 		r.Route("/account", func(r chi.Router) {
-			// r.Patch("/modify/email", func(w http.ResponseWriter, r *http.Request) {})
-			// r.Patch("/modify/password", func(w http.ResponseWriter, r *http.Request) {})
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Patch("/modify/email", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleChangeEmail(w, r, pbClient, casbinEnforcer, SMTPClient)
+			})
+
+			r.Post("/modify/email/confirm", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleConfirmEmailChange(w, r, pbClient)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Post("/verify/resend", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleResendVerification(w, r, pbClient, SMTPClient)
+			})
+
+			r.Post("/verify/confirm", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleConfirmVerification(w, r, pbClient)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Patch("/modify/password", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleChangePassword(w, r, pbClient, casbinEnforcer, loginGuard)
+			})
+
+			r.Post("/password/reset", func(w http.ResponseWriter, r *http.Request) {
+				user.HandlePasswordResetRequest(w, r, pbClient, SMTPClient)
+			})
+
+			r.Post("/password/reset/confirm", func(w http.ResponseWriter, r *http.Request) {
+				user.HandlePasswordResetConfirm(w, r, pbClient)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Post("/mfa/enroll", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleMFAEnroll(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Post("/mfa/verify", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleMFAVerify(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Post("/mfa/recovery", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleMFARecoveryCodes(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Delete("/mfa", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleMFADisable(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "view", "own")).Get("/passkeys", func(w http.ResponseWriter, r *http.Request) {
+				user.HandleListPasskeys(w, r, pbClient, casbinEnforcer, passkeys)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Post("/passkeys/register/begin", func(w http.ResponseWriter, r *http.Request) {
+				user.HandlePasskeyRegisterBegin(w, r, pbClient, casbinEnforcer, passkeys)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Post("/passkeys/register/finish", func(w http.ResponseWriter, r *http.Request) {
+				user.HandlePasskeyRegisterFinish(w, r, pbClient, casbinEnforcer, passkeys)
+			})
+
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Delete("/passkeys/{id}", func(w http.ResponseWriter, r *http.Request) {
+				credentialId := chi.URLParam(r, "id")
+				user.HandleRemovePasskey(w, r, credentialId, pbClient, casbinEnforcer, passkeys)
+			})
 
 			// for name, birthdate, gender
-			r.Patch("/update", func(w http.ResponseWriter, r *http.Request) {
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Patch("/update", func(w http.ResponseWriter, r *http.Request) {
 				user.HandlUpdateProfile(w, r, pbClient, casbinEnforcer)
 			})
 
 			// for avatar only
-			r.Patch("/update/avatar", func(w http.ResponseWriter, r *http.Request) {
+			r.With(auth.RequirePermission(casbinEnforcer, "users", "update", "own")).Patch("/update/avatar", func(w http.ResponseWriter, r *http.Request) {
 				user.HandleUpdateAvatar(w, r, pbClient, casbinEnforcer)
 			})
 		})
@@ -226,45 +560,58 @@ func setupRouter() *chi.Mux {
 
 	// Lab_book route
 	r.Route("/labbook", func(r chi.Router) {
-		r.Post("/upload", func(w http.ResponseWriter, r *http.Request) {
-			labbook.HandleLabBookUpload(w, r, pbClient, casbinEnforcer)
+		// Only users with a verified email address can upload or share lab books
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireVerifiedEmail())
+
+			r.With(auth.RequirePermission(casbinEnforcer, "lab_books", "create", "own")).Post("/upload", func(w http.ResponseWriter, r *http.Request) {
+				labbook.HandleLabBookUpload(w, r, pbClient, casbinEnforcer)
+			})
+
+			r.With(
+				auth.RequirePermission(casbinEnforcer, "lab_books", "update", "share"),
+				auth.RequireRecordPermission(casbinEnforcer, pbClient, "lab_books", "update", "id", auth.LabbookRecord),
+			).Post("/share/{id}", func(w http.ResponseWriter, r *http.Request) {
+				labbookId := chi.URLParam(r, "id")
+				labbook.HandleShareLabbook(w, r, labbookId, pbClient, casbinEnforcer)
+			})
 		})
 
-		r.Get("/upload/history", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequirePermission(casbinEnforcer, "lab_books", "view", "own")).Get("/upload/history", func(w http.ResponseWriter, r *http.Request) {
 			labbook.HandleLabbookUploadHistory(w, r, pbClient, casbinEnforcer)
 		})
 
-		r.Post("/share", func(w http.ResponseWriter, r *http.Request) {
-			labbook.HandleShareLabbook(w, r, pbClient, casbinEnforcer)
-		})
-		r.Get("/shared/list", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequirePermission(casbinEnforcer, "lab_books", "view", "shared")).Get("/shared/list", func(w http.ResponseWriter, r *http.Request) {
 			labbook.GetSharedList(w, r, pbClient, casbinEnforcer)
 		})
 
-		r.Get("/view/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequireRecordPermission(casbinEnforcer, pbClient, "lab_books", "view", "id", auth.LabbookRecord)).Get("/view/{id}", func(w http.ResponseWriter, r *http.Request) {
 			labbookId := chi.URLParam(r, "id")
 			labbook.HandleLabBookView(w, r, labbookId, pbClient, casbinEnforcer)
 		})
 
-		r.Delete("/remove/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequireRecordPermission(casbinEnforcer, pbClient, "lab_books", "delete", "id", auth.LabbookRecord)).Delete("/remove/{id}", func(w http.ResponseWriter, r *http.Request) {
 			labbookId := chi.URLParam(r, "id")
 			labbook.HandleLabBookRemove(w, r, labbookId, pbClient, casbinEnforcer)
 		})
 
-		r.Patch("/review", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequirePermission(casbinEnforcer, "lab_books", "update", "status")).Patch("/review", func(w http.ResponseWriter, r *http.Request) {
 			labbook.HandleLabBookReview(w, r, pbClient, casbinEnforcer)
 		})
 
-		r.Get("/review/pending", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequirePermission(casbinEnforcer, "lab_books", "update", "review")).Get("/review/pending", func(w http.ResponseWriter, r *http.Request) {
 			labbook.GetPendingReviews(w, r, pbClient, casbinEnforcer)
 		})
 
-		r.Get("/reviewers", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequirePermission(casbinEnforcer, "lab_books", "create", "own")).Get("/reviewers", func(w http.ResponseWriter, r *http.Request) {
 			labbook.GetAvailiableReviewers(w, r, pbClient, casbinEnforcer)
 		})
 	})
 
 	r.Route("/roles", func(r chi.Router) {
+		// The role list returns the fields named by the list scopes
+		casbin.RegisterPermission("roles", "list:id,name,description,type,parent,permissions")
+
 		r.Get("/list", func(w http.ResponseWriter, r *http.Request) {
 			role.HandleRoleList(w, r, pbClient, casbinEnforcer)
 		})
@@ -276,11 +623,15 @@ func setupRouter() *chi.Mux {
 
 		// r.Patch("/update", func(w http.ResponseWriter, r *http.Request) {})
 
-		r.Post("/create", func(w http.ResponseWriter, r *http.Request) {
-			role.HandleCreateNewRole(w, r, pbClient, casbinEnforcer)
+		r.With(auth.RequirePermission(casbinEnforcer, "roles", "create", "custom")).Post("/create", func(w http.ResponseWriter, r *http.Request) {
+			role.HandleCreateNewRole(w, r, pbClient, casbinEnforcer, permissionCatalog)
+		})
+
+		r.With(auth.RequirePermission(casbinEnforcer, "roles", "create", "custom")).Get("/catalog", func(w http.ResponseWriter, r *http.Request) {
+			role.HandleRoleCatalog(w, r, permissionCatalog)
 		})
 
-		r.Delete("/remove/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r.With(auth.RequirePermission(casbinEnforcer, "roles", "delete", "custom")).Delete("/remove/{id}", func(w http.ResponseWriter, r *http.Request) {
 			deleteRoleId := chi.URLParam(r, "id")
 			role.HandleDeleteRole(w, r, deleteRoleId, pbClient, casbinEnforcer)
 		})
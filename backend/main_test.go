@@ -0,0 +1,238 @@
+package main
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	testSigningKey = "pocketbase-signing-key"
+	testUsersId    = "_pb_users_auth_"
+	testUserId     = "user0000000001"
+)
+
+func signTestToken(t *testing.T, key, collectionId string, expires time.Time) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":           testUserId,
+		"type":         "auth",
+		"collectionId": collectionId,
+		"exp":          expires.Unix(),
+		"refreshable":  true,
+	})
+	signed, err := token.SignedString([]byte(key))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+// setupAuthStub points the middleware globals at a stub PocketBase that verifies token signatures on
+// auth-refresh, knows the sessions of the given tokens and serves an active, verified user and two lab books:
+// "ownlabbook0001" created by that user and "foreignlabbook" created by another one.
+func setupAuthStub(t *testing.T, sessionTokens ...string) {
+	t.Helper()
+
+	sessions := make(map[string]bool, len(sessionTokens))
+	for _, token := range sessionTokens {
+		sessions[tools.HashToken(token)] = true
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/collections/users/auth-refresh":
+			rawToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			claims := jwt.MapClaims{}
+			if _, err := jwt.ParseWithClaims(rawToken, claims, func(*jwt.Token) (interface{}, error) {
+				return []byte(testSigningKey), nil
+			}); err != nil {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"token": rawToken, "record": map[string]string{"id": claims["id"].(string)}})
+
+		case r.URL.Path == "/api/collections/sessions/records":
+			items := []pocketbase.Session{}
+			for tokenHash := range sessions {
+				if strings.Contains(r.URL.Query().Get("filter"), tokenHash) {
+					items = append(items, pocketbase.Session{Id: "session1", UserId: testUserId, TokenHash: tokenHash})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+
+		case r.URL.Path == "/api/collections/users/records/"+testUserId:
+			json.NewEncoder(w).Encode(pocketbase.User{Id: testUserId, RoleId: "0003", Status: pocketbase.UserStatusActive, Verified: true})
+
+		case r.Method == http.MethodGet && r.URL.Path == "/api/collections/lab_books/records/ownlabbook0001":
+			json.NewEncoder(w).Encode(pocketbase.Labbook{Id: "ownlabbook0001", Creator: testUserId})
+
+		case r.Method == http.MethodGet && r.URL.Path == "/api/collections/lab_books/records/foreignlabbook":
+			json.NewEncoder(w).Encode(pocketbase.Labbook{Id: "foreignlabbook", Creator: "other000000001", ShareWith: []string{"friend00000001"}})
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	pbClient = &pocketbase.PocketBaseClient{
+		BaseURL:           server.URL,
+		HTTPClient:        server.Client(),
+		UserInfoCache:     cache.New(time.Minute, time.Minute),
+		TokenCache:        cache.New(time.Minute, time.Minute),
+		UsersCollectionId: testUsersId,
+	}
+	sessionRegistry = auth.NewSessionRegistry(pbClient)
+	revocationList = auth.NewRevocationList()
+}
+
+func TestJWTExpirationMiddleware(t *testing.T) {
+	hour := time.Now().Add(time.Hour)
+	valid := signTestToken(t, testSigningKey, testUsersId, hour)
+	revoked := signTestToken(t, testSigningKey, testUsersId, hour.Add(time.Minute))
+	forged := signTestToken(t, "not-the-pocketbase-key", testUsersId, hour)
+	expired := signTestToken(t, testSigningKey, testUsersId, time.Now().Add(-time.Minute))
+	otherCollection := signTestToken(t, testSigningKey, "pbc_3142635823", hour)
+	withoutSession := signTestToken(t, testSigningKey, testUsersId, hour.Add(2*time.Minute))
+
+	setupAuthStub(t, valid, revoked, forged, expired, otherCollection)
+	if err := revocationList.Revoke(revoked); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	var principal *auth.Principal
+	protected := jwtExpirationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = auth.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"valid token", "/user/view/" + testUserId, "Bearer " + valid, http.StatusOK},
+		{"missing token", "/user/view/" + testUserId, "", http.StatusUnauthorized},
+		{"not a bearer token", "/user/view/" + testUserId, "Basic " + valid, http.StatusUnauthorized},
+		{"forged token", "/user/view/" + testUserId, "Bearer " + forged, http.StatusUnauthorized},
+		{"expired token", "/user/view/" + testUserId, "Bearer " + expired, http.StatusUnauthorized},
+		{"token of another collection", "/user/view/" + testUserId, "Bearer " + otherCollection, http.StatusUnauthorized},
+		{"token without session", "/user/view/" + testUserId, "Bearer " + withoutSession, http.StatusUnauthorized},
+		{"revoked token", "/user/view/" + testUserId, "Bearer " + revoked, http.StatusUnauthorized},
+		{"public route", "/login/account", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal = nil
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			protected.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.want, strings.TrimSpace(rec.Body.String()))
+			}
+			if tt.header != "" && tt.want == http.StatusOK && (principal == nil || principal.UserId != testUserId) {
+				t.Errorf("principal = %+v, want user %s", principal, testUserId)
+			}
+		})
+	}
+}
+
+// TestPermissionCatalog checks that the catalog holds the permissions the routes check on top of the defaults.
+func TestPermissionCatalog(t *testing.T) {
+	setupRouter()
+
+	catalog, err := casbin.LoadCatalog("./defaultPermission.json")
+	if err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+
+	tests := []struct {
+		resource string
+		action   string
+		scope    string
+	}{
+		{"users", "view", "own"},
+		{"users", "list", "avatar"},
+		{"users", "list", "status"},
+		{"roles", "list", "name"},
+		{"roles", "list", "permissions"},
+		{"users", "create", casbin.RoleIdScope},
+		{"users", "update", casbin.RoleIdScope},
+		{"users", "delete", "*"},
+		{"users", "impersonate", "*"},
+		{"roles", "create", "custom"},
+		{"roles", "delete", "custom"},
+		{"lab_books", "update", "share"},
+		{"lab_books", "update", "review"},
+		{"lab_books", "update", "status"},
+		{"lab_books", "delete", "own"},
+	}
+
+	for _, tt := range tests {
+		if scopes := catalog.Resources[tt.resource][tt.action]; !tools.Contains(scopes, tt.scope) {
+			t.Errorf("catalog %s %s scopes = %v, want %q", tt.resource, tt.action, scopes, tt.scope)
+		}
+	}
+}
+
+// TestShareLabbookAuthorization checks that sharing is authorized against the lab book before anything else:
+// a lab book of another user answers 403 even when the recipient already has access to it.
+func TestShareLabbookAuthorization(t *testing.T) {
+	token := signTestToken(t, testSigningKey, testUsersId, time.Now().Add(time.Hour))
+	setupAuthStub(t, token)
+
+	var err error
+	casbinEnforcer, err = casbin.InitializeCasbin([][]interface{}{
+		{"0003", "lab_books", "update", "share"},
+		{"0003", "lab_books", "update", "own"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("InitializeCasbin() error = %v", err)
+	}
+	router := setupRouter()
+
+	tests := []struct {
+		name      string
+		labbookId string
+		recipient string
+		want      int
+	}{
+		{"own lab book, recipient already has access", "ownlabbook0001", testUserId, http.StatusConflict},
+		{"foreign lab book, recipient already has access", "foreignlabbook", "friend00000001", http.StatusForbidden},
+		{"foreign lab book", "foreignlabbook", "stranger000001", http.StatusForbidden},
+		{"missing lab book", "missinglabbook", "stranger000001", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.NewReader(`{"recipient_id": "` + tt.recipient + `"}`)
+			req := httptest.NewRequest(http.MethodPost, "/labbook/share/"+tt.labbookId, body)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.want, strings.TrimSpace(rec.Body.String()))
+			}
+		})
+	}
+}
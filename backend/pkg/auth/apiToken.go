@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix marks personal access tokens, so they are never mistaken for PocketBase JWTs.
+const APITokenPrefix = "alz_"
+
+var ErrInvalidAPIToken = errors.New("invalid or expired api token")
+
+// IsAPIToken reports whether a raw bearer token is a personal access token.
+func IsAPIToken(rawToken string) bool {
+	return strings.HasPrefix(rawToken, APITokenPrefix)
+}
+
+// GenerateAPIToken returns a new random personal access token.
+func GenerateAPIToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	return APITokenPrefix + hex.EncodeToString(raw), nil
+}
+
+// ResolveAPIToken looks up a personal access token and loads the user it belongs to.
+//
+// The principal is limited to the token's scopes on top of the user's role.
+func ResolveAPIToken(pbClient *pocketbase.PocketBaseClient, rawToken string) (*Principal, error) {
+	token, err := pbClient.FindAPITokenByHash(tools.HashToken(rawToken))
+	if err != nil {
+		return nil, err
+	}
+	if token.Id == "" {
+		return nil, ErrInvalidAPIToken
+	}
+
+	if token.Expires != "" {
+		expires, err := time.Parse(pocketbase.DateLayout, token.Expires)
+		if err != nil || time.Now().After(expires) {
+			return nil, ErrInvalidAPIToken
+		}
+	}
+
+	userInfo, err := pbClient.ViewUser(token.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user %s: %w", token.UserId, err)
+	}
+	if !userInfo.IsActive() {
+		return nil, ErrUserNotActive
+	}
+
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return &Principal{
+		UserId:     userInfo.Id,
+		RoleId:     userInfo.RoleId,
+		SettingId:  userInfo.SettingId,
+		Token:      rawToken,
+		Verified:   userInfo.Verified,
+		APITokenId: token.Id,
+		Scopes:     scopes,
+	}, nil
+}
+
+// ParseScope splits a token scope written as "resource:action:scope".
+//
+// The scope part may hold several comma separated scopes and is required,
+// the same way convertCasbinFormat reads a role's permissions document.
+func ParseScope(value string) (resource string, action string, scopes []string, err error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", nil, fmt.Errorf("invalid scope %q, expected resource:action:scope", value)
+	}
+
+	for _, s := range strings.Split(parts[2], ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return "", "", nil, fmt.Errorf("invalid scope %q, expected resource:action:scope", value)
+	}
+
+	return parts[0], parts[1], scopes, nil
+}
+
+// scopeAllows checks the permission against the token scopes of the principal.
+func (principal *Principal) scopeAllows(resource, action, scope string) (hasPermission bool, starPermission bool) {
+	for _, s := range principal.tokenScopes(resource, action) {
+		if s == "*" {
+			return true, true
+		}
+		if s == scope {
+			hasPermission = true
+		}
+	}
+
+	return hasPermission, false
+}
+
+// tokenScopes returns the scopes the principal's token grants for a resource and action.
+func (principal *Principal) tokenScopes(resource, action string) []string {
+	var scopes []string
+	for _, value := range principal.Scopes {
+		tokenResource, tokenAction, tokenScopes, err := ParseScope(value)
+		if err != nil || tokenResource != resource || tokenAction != action {
+			continue
+		}
+
+		for _, s := range tokenScopes {
+			if !tools.Contains(scopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+
+	return scopes
+}
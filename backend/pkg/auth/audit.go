@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"log"
+)
+
+// Actions recorded in the audit trail
+const (
+	AuditImpersonationStart   = "impersonation_start"
+	AuditImpersonationStop    = "impersonation_stop"
+	AuditImpersonationRequest = "impersonation_request"
+	AuditRoleChange           = "role_change"
+)
+
+// Audit records an entry of the audit trail in the background, failures are only logged.
+func Audit(pbClient *pocketbase.PocketBaseClient, entry pocketbase.AuditLog) {
+	log.Printf("audit: %s actor=%s user=%s %s %s status=%d ip=%s %s",
+		entry.Action, entry.ActorId, entry.UserId, entry.Method, entry.Path, entry.Status, entry.IP, entry.Details)
+
+	go func() {
+		if err := pbClient.CreateAuditLog(entry); err != nil {
+			log.Println("Failed to store audit log:", err)
+		}
+	}()
+}
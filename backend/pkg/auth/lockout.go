@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// Failed logins are forgotten after this long without a new failure.
+	loginFailureWindow = 24 * time.Hour
+	// Number of free attempts before the exponential backoff starts.
+	loginBackoffAfter = 3
+	maxLoginBackoff   = 5 * time.Minute
+	// An account is locked after this many failures, an IP address after ipLockoutAfter failures.
+	accountLockoutAfter = 10
+	ipLockoutAfter      = 50
+	LoginLockoutPeriod  = 15 * time.Minute
+)
+
+type loginFailures struct {
+	Count       int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// LoginGuard tracks failed logins per account and per IP address,
+// slowing down attempts with an exponential backoff and locking out after too many failures.
+type LoginGuard struct {
+	mu       sync.Mutex
+	accounts *cache.Cache
+	ips      *cache.Cache
+}
+
+// NewLoginGuard creates a guard without any recorded failures.
+func NewLoginGuard() *LoginGuard {
+	return &LoginGuard{
+		accounts: cache.New(loginFailureWindow, 10*time.Minute),
+		ips:      cache.New(loginFailureWindow, 10*time.Minute),
+	}
+}
+
+// RetryAfter returns how long the caller must wait before the next login attempt, 0 if it may try now.
+func (lg *LoginGuard) RetryAfter(email, ip string) time.Duration {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	now := time.Now()
+	wait := retryAfter(lg.accounts, accountKey(email), now)
+	if ipWait := retryAfter(lg.ips, ip, now); ipWait > wait {
+		wait = ipWait
+	}
+
+	return wait
+}
+
+// Failure records a failed login.
+//
+// It returns true when this failure locked the account, so the owner can be notified.
+func (lg *LoginGuard) Failure(email, ip string) (accountLocked bool) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	now := time.Now()
+	accountLocked = recordFailure(lg.accounts, accountKey(email), accountLockoutAfter, now)
+	recordFailure(lg.ips, ip, ipLockoutAfter, now)
+
+	return accountLocked
+}
+
+// Success forgets the failed logins of an account.
+func (lg *LoginGuard) Success(email string) {
+	lg.accounts.Delete(accountKey(email))
+}
+
+// Unlock lifts the lockout and backoff of an account.
+func (lg *LoginGuard) Unlock(email string) {
+	lg.accounts.Delete(accountKey(email))
+}
+
+// UnlockIP lifts the lockout and backoff of an IP address.
+func (lg *LoginGuard) UnlockIP(ip string) {
+	lg.ips.Delete(ip)
+}
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func retryAfter(failures *cache.Cache, key string, now time.Time) time.Duration {
+	cached, found := failures.Get(key)
+	if !found {
+		return 0
+	}
+	entry := cached.(loginFailures)
+
+	if now.Before(entry.LockedUntil) {
+		return entry.LockedUntil.Sub(now)
+	}
+
+	if entry.Count < loginBackoffAfter {
+		return 0
+	}
+
+	backoff := maxLoginBackoff
+	if shift := entry.Count - loginBackoffAfter; shift < 16 {
+		if d := time.Duration(1<<shift) * time.Second; d < maxLoginBackoff {
+			backoff = d
+		}
+	}
+
+	if next := entry.LastFailure.Add(backoff); now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+func recordFailure(failures *cache.Cache, key string, lockoutAfter int, now time.Time) (locked bool) {
+	var entry loginFailures
+	if cached, found := failures.Get(key); found {
+		entry = cached.(loginFailures)
+	}
+
+	entry.Count++
+	entry.LastFailure = now
+
+	// Start over once the lockout is set, the next failures after it count towards a new one
+	if entry.Count >= lockoutAfter {
+		entry = loginFailures{LastFailure: now, LockedUntil: now.Add(LoginLockoutPeriod)}
+		locked = true
+	}
+
+	failures.Set(key, entry, cache.DefaultExpiration)
+	return locked
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ErrRecordNotFound is returned by a RecordResolver when the targeted record does not exist.
+var ErrRecordNotFound = errors.New("record not found")
+
+// Scopes with a meaning for a single record, other scopes (e.g. "status" or role IDs) grant no record access.
+const (
+	ScopeAny    = "*"
+	ScopeOwn    = "own"
+	ScopeShared = "shared"
+)
+
+// Record is what object-level authorization needs to know about the record a request targets.
+type Record struct {
+	// OwnerId is the user the record belongs to: the creator of a lab book, or the user themselves.
+	OwnerId string
+	// SharedWith are the users the record is shared with.
+	SharedWith []string
+	// ReviewerId is the user asked to review the record, who may only view it (see AuthorizeRecord).
+	ReviewerId string
+}
+
+// reviewerActions are the actions the reviewer of a record may perform on it under the "shared" scope.
+var reviewerActions = []string{"view"}
+
+// RecordResolver loads the record with the given ID, returning ErrRecordNotFound if there is none.
+type RecordResolver func(pbClient *pocketbase.PocketBaseClient, id string) (Record, error)
+
+// ScopeAllowsRecord reports whether a scope lets userId act on the record:
+// "*" allows any record, "own" the records the user owns and "shared" the records shared with them.
+func ScopeAllowsRecord(scope, userId string, record Record) bool {
+	switch scope {
+	case ScopeAny:
+		return true
+	case ScopeOwn:
+		return record.OwnerId != "" && record.OwnerId == userId
+	case ScopeShared:
+		for _, sharedWith := range record.SharedWith {
+			if sharedWith == userId {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AuthorizeRecord checks a permission on a single record: the principal needs one of the scopes
+// of the action on the resource to allow this record (see ScopeAllowsRecord).
+// The reviewer of the record counts as a user it is shared with, for the reviewerActions only.
+func (principal *Principal) AuthorizeRecord(ce *casbin.CasbinEnforcer, pbClient *pocketbase.PocketBaseClient, resource, action string, record Record) bool {
+	// FetchScopes fails when no scope is granted at all
+	scopes, err := principal.FetchScopes(ce, pbClient, resource, action)
+	if err != nil {
+		return false
+	}
+
+	reviewing := record.ReviewerId != "" && record.ReviewerId == principal.UserId && tools.Contains(reviewerActions, action)
+
+	for _, scope := range scopes {
+		if ScopeAllowsRecord(scope, principal.UserId, record) || (reviewing && scope == ScopeShared) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequireRecordPermission returns a chi middleware that only lets the request through when the principal
+// may perform the action on the record whose ID is in the idParam URL parameter.
+// The record is loaded with resolve, 404 Not Found is returned if it does not exist.
+//
+// It must be mounted after the auth middleware that stores the principal in the request context.
+func RequireRecordPermission(ce *casbin.CasbinEnforcer, pbClient *pocketbase.PocketBaseClient, resource, action, idParam string, resolve RecordResolver) func(http.Handler) http.Handler {
+	// Every resolver has an owner, not every one shares records
+	casbin.RegisterPermission(resource, action+":"+ScopeOwn)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			id := chi.URLParam(r, idParam)
+			if id == "" {
+				http.Error(w, "Missing record ID", http.StatusBadRequest)
+				return
+			}
+
+			record, err := resolve(pbClient, id)
+			if errors.Is(err, ErrRecordNotFound) {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to load record", http.StatusInternalServerError)
+				return
+			}
+
+			if !principal.AuthorizeRecord(ce, pbClient, resource, action, record) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LabbookRecord resolves a lab book: it is owned by its creator, shared with its share_with users
+// and reviewed by its reviewer, who needs to read it to review it.
+func LabbookRecord(pbClient *pocketbase.PocketBaseClient, id string) (Record, error) {
+	labbook, err := pbClient.ViewLabbook(id, []string{"id", "creator", "reviewer", "share_with"})
+	if err != nil {
+		return Record{}, err
+	}
+	if labbook.Id == "" {
+		return Record{}, ErrRecordNotFound
+	}
+
+	return Record{OwnerId: labbook.Creator, SharedWith: labbook.ShareWith, ReviewerId: labbook.Reviewer}, nil
+}
+
+// UserRecord resolves a user, who owns their own record.
+func UserRecord(pbClient *pocketbase.PocketBaseClient, id string) (Record, error) {
+	exists, err := pbClient.CheckUserExists(id)
+	if err != nil {
+		return Record{}, err
+	}
+	if !exists {
+		return Record{}, ErrRecordNotFound
+	}
+
+	return Record{OwnerId: id}, nil
+}
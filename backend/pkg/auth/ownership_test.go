@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	ownerId    = "owner000000001"
+	friendId   = "friend00000001"
+	reviewerId = "review00000001"
+	strangerId = "strang00000001"
+)
+
+func TestScopeAllowsRecord(t *testing.T) {
+	labbook := Record{OwnerId: ownerId, SharedWith: []string{friendId}, ReviewerId: reviewerId}
+
+	tests := []struct {
+		name   string
+		scope  string
+		userId string
+		record Record
+		want   bool
+	}{
+		{"any record, foreign", ScopeAny, strangerId, labbook, true},
+		{"any record, own", ScopeAny, ownerId, labbook, true},
+		{"own record", ScopeOwn, ownerId, labbook, true},
+		{"own scope, shared record", ScopeOwn, friendId, labbook, false},
+		{"own scope, foreign record", ScopeOwn, strangerId, labbook, false},
+		{"own scope, record without owner", ScopeOwn, "", Record{}, false},
+		{"shared record", ScopeShared, friendId, labbook, true},
+		{"shared scope, reviewer", ScopeShared, reviewerId, labbook, false},
+		{"shared scope, own record", ScopeShared, ownerId, labbook, false},
+		{"shared scope, foreign record", ScopeShared, strangerId, labbook, false},
+		{"shared scope, record shared with nobody", ScopeShared, friendId, Record{OwnerId: ownerId}, false},
+		{"scope without record meaning", "status", ownerId, labbook, false},
+		{"role ID scope", "0003", ownerId, labbook, false},
+		{"empty scope", "", ownerId, labbook, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScopeAllowsRecord(tt.scope, tt.userId, tt.record); got != tt.want {
+				t.Errorf("ScopeAllowsRecord(%q, %s) = %v, want %v", tt.scope, tt.userId, got, tt.want)
+			}
+		})
+	}
+}
+
+// newLabbookStub returns a client for a PocketBase stub serving the given lab_books records.
+// A record with the ID "broken" answers 500 Internal Server Error.
+func newLabbookStub(t *testing.T, labbooks ...pocketbase.Labbook) *pocketbase.PocketBaseClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := strings.CutPrefix(r.URL.Path, "/api/collections/lab_books/records/")
+		if !ok {
+			t.Errorf("unexpected PocketBase request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		if id == "broken" {
+			http.Error(w, "database is locked", http.StatusInternalServerError)
+			return
+		}
+		for _, labbook := range labbooks {
+			if labbook.Id == id {
+				json.NewEncoder(w).Encode(labbook)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return &pocketbase.PocketBaseClient{BaseURL: server.URL, HTTPClient: server.Client()}
+}
+
+func TestLabbookRecord(t *testing.T) {
+	pbClient := newLabbookStub(t,
+		pocketbase.Labbook{Id: "reviewed", Creator: ownerId, Reviewer: reviewerId, ShareWith: []string{friendId}},
+		pocketbase.Labbook{Id: "private", Creator: ownerId},
+	)
+
+	tests := []struct {
+		name    string
+		id      string
+		want    Record
+		wantErr error
+	}{
+		{"shared and reviewed", "reviewed", Record{OwnerId: ownerId, SharedWith: []string{friendId}, ReviewerId: reviewerId}, nil},
+		{"private", "private", Record{OwnerId: ownerId}, nil},
+		{"missing", "missing", Record{}, ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LabbookRecord(pbClient, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LabbookRecord() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LabbookRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("PocketBase failure", func(t *testing.T) {
+		if _, err := LabbookRecord(pbClient, "broken"); err == nil || errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("LabbookRecord() error = %v, want a failure other than ErrRecordNotFound", err)
+		}
+	})
+}
+
+// TestLabbookAccess checks who may act on a lab book when every user's role grants the "own" and "shared"
+// scopes: the reviewer may view it like the users it is shared with, but not update or delete it.
+func TestLabbookAccess(t *testing.T) {
+	pbClient := newLabbookStub(t,
+		pocketbase.Labbook{Id: "reviewed", Creator: ownerId, Reviewer: reviewerId, ShareWith: []string{friendId}},
+	)
+	record, err := LabbookRecord(pbClient, "reviewed")
+	if err != nil {
+		t.Fatalf("LabbookRecord() error = %v", err)
+	}
+
+	ce, err := casbin.InitializeCasbin([][]interface{}{
+		{"member", "lab_books", "view", "own"},
+		{"member", "lab_books", "view", "shared"},
+		{"member", "lab_books", "update", "own"},
+		{"member", "lab_books", "update", "shared"},
+		{"member", "lab_books", "delete", "own"},
+		{"member", "lab_books", "delete", "shared"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("InitializeCasbin() error = %v", err)
+	}
+	pbClient.UserInfoCache = cache.New(time.Minute, time.Minute)
+	for _, userId := range []string{ownerId, friendId, reviewerId, strangerId} {
+		pbClient.UserInfoCache.Set(userId, pocketbase.User{Id: userId, RoleId: "member"}, cache.DefaultExpiration)
+	}
+
+	// Actions each user may perform on the lab book
+	tests := []struct {
+		userId string
+		want   []string
+	}{
+		{ownerId, []string{"view", "update", "delete"}},
+		{friendId, []string{"view", "update", "delete"}},
+		{reviewerId, []string{"view"}},
+		{strangerId, nil},
+	}
+
+	for _, tt := range tests {
+		principal := &Principal{UserId: tt.userId, RoleId: "member"}
+
+		var got []string
+		for _, action := range []string{"view", "update", "delete"} {
+			if principal.AuthorizeRecord(ce, pbClient, "lab_books", action, record) {
+				got = append(got, action)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("actions allowed to %s = %v, want %v", tt.userId, got, tt.want)
+		}
+	}
+}
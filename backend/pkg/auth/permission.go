@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"fmt"
+	"net/http"
+)
+
+// Authorize checks a permission for the principal's role.
+//
+// Requests made with a personal access token are additionally limited to the token's scopes.
+func (principal *Principal) Authorize(ce *casbin.CasbinEnforcer, resource, action, scope string) (hasPermission bool, starPermission bool, err error) {
+	hasPermission, starPermission, err = ce.VerifyRolePermission(principal.RoleId, casbin.PermissionConfig{
+		Resources: resource,
+		Actions:   action,
+		Scopes:    scope,
+	})
+	if err != nil || !hasPermission || principal.Scopes == nil {
+		return hasPermission, starPermission, err
+	}
+
+	tokenPermission, tokenStarPermission := principal.scopeAllows(resource, action, scope)
+	return tokenPermission, starPermission && tokenStarPermission, nil
+}
+
+// FetchScopes returns the scopes the principal's role has for a resource and action.
+//
+// Requests made with a personal access token only keep the scopes both the role and the token grant:
+// a "*" on either side stands for the scopes of the other.
+func (principal *Principal) FetchScopes(ce *casbin.CasbinEnforcer, pbClient *pocketbase.PocketBaseClient, resource, action string) ([]string, error) {
+	scopes, err := ce.ScopeFetcher(pbClient, principal.UserId, casbin.PermissionConfig{
+		Resources: resource,
+		Actions:   action,
+	})
+	if err != nil || principal.Scopes == nil {
+		return scopes, err
+	}
+
+	tokenScopes := principal.tokenScopes(resource, action)
+
+	var allowed []string
+	switch {
+	case tools.Contains(tokenScopes, "*"):
+		allowed = scopes
+	case tools.Contains(scopes, "*"):
+		allowed = tokenScopes
+	default:
+		for _, scope := range scopes {
+			if tools.Contains(tokenScopes, scope) {
+				allowed = append(allowed, scope)
+			}
+		}
+	}
+
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("no scopes granted by api token for resource: %s, action: %s", resource, action)
+	}
+
+	return allowed, nil
+}
+
+// RequirePermission returns a chi middleware that only lets the request through
+// when the principal's role has the given permission (or the '*' scope for it).
+//
+// It must be mounted after the auth middleware that stores the principal in the request context.
+func RequirePermission(ce *casbin.CasbinEnforcer, resource, action, scope string) func(http.Handler) http.Handler {
+	casbin.RegisterPermission(resource, action+":"+scope)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			hasPermission, _, err := principal.Authorize(ce, resource, action, scope)
+			if err != nil || !hasPermission {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
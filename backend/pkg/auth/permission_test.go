@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	adminId   = "admin000000001"
+	studentId = "studnt00000001"
+)
+
+// newPermissionTest returns an enforcer where the admin role views any lab book and the student role
+// its own and shared ones, and a client that knows the role of both users.
+func newPermissionTest(t *testing.T) (*casbin.CasbinEnforcer, *pocketbase.PocketBaseClient) {
+	t.Helper()
+
+	ce, err := casbin.InitializeCasbin([][]interface{}{
+		{"admin", "lab_books", "view", "*"},
+		{"student", "lab_books", "view", "own"},
+		{"student", "lab_books", "view", "shared"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("InitializeCasbin() error = %v", err)
+	}
+
+	pbClient := &pocketbase.PocketBaseClient{UserInfoCache: cache.New(time.Minute, time.Minute)}
+	pbClient.UserInfoCache.Set(adminId, pocketbase.User{Id: adminId, RoleId: "admin"}, cache.DefaultExpiration)
+	pbClient.UserInfoCache.Set(studentId, pocketbase.User{Id: studentId, RoleId: "student"}, cache.DefaultExpiration)
+
+	return ce, pbClient
+}
+
+func TestFetchScopes(t *testing.T) {
+	ce, pbClient := newPermissionTest(t)
+
+	tests := []struct {
+		name    string
+		userId  string
+		scopes  []string
+		want    []string
+		wantErr bool
+	}{
+		{"login token", studentId, nil, []string{"own", "shared"}, false},
+		{"login token, any record", adminId, nil, []string{"*"}, false},
+		{"token narrowing the role", studentId, []string{"lab_books:view:own"}, []string{"own"}, false},
+		{"token narrowing a role with *", adminId, []string{"lab_books:view:own,shared"}, []string{"own", "shared"}, false},
+		{"token with * on a narrower role", studentId, []string{"lab_books:view:*"}, []string{"own", "shared"}, false},
+		{"token with * on a role with *", adminId, []string{"lab_books:view:*"}, []string{"*"}, false},
+		{"token scope the role lacks", studentId, []string{"lab_books:view:public"}, nil, true},
+		{"token for another action", adminId, []string{"lab_books:update:own"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := &Principal{UserId: tt.userId, Scopes: tt.scopes}
+			if tt.scopes != nil {
+				principal.APITokenId = "token0000000001"
+			}
+
+			got, err := principal.FetchScopes(ce, pbClient, "lab_books", "view")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchScopes() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FetchScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAuthorizeRecordWithNarrowToken checks that a token scoped "own" on a role with "*"
+// reaches the records of its owner, and only those.
+func TestAuthorizeRecordWithNarrowToken(t *testing.T) {
+	ce, pbClient := newPermissionTest(t)
+	principal := &Principal{UserId: adminId, APITokenId: "token0000000001", Scopes: []string{"lab_books:view:own"}}
+
+	if !principal.AuthorizeRecord(ce, pbClient, "lab_books", "view", Record{OwnerId: adminId}) {
+		t.Error("AuthorizeRecord() denied the token its owner's lab book")
+	}
+	if principal.AuthorizeRecord(ce, pbClient, "lab_books", "view", Record{OwnerId: studentId, SharedWith: []string{adminId}}) {
+		t.Error("AuthorizeRecord() allowed the token a lab book shared with its owner")
+	}
+}
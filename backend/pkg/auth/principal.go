@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUserNotActive is returned for users that are not allowed to sign in, e.g. pending approval.
+var ErrUserNotActive = errors.New("user is not active")
+
+// Principal is the authenticated caller of a request, resolved once by the auth middleware.
+type Principal struct {
+	UserId    string
+	RoleId    string
+	SettingId string
+	Token     string
+	// Verified is true once the user confirmed their email address.
+	Verified bool
+	// APITokenId is set when the request was made with a personal access token.
+	APITokenId string
+	// Scopes limits a personal access token to a subset of the role's permissions, nil for login tokens.
+	Scopes []string
+	// ImpersonatorId is set when an admin makes the request while impersonating the user.
+	ImpersonatorId string
+}
+
+// IsAPIToken reports whether the principal authenticated with a personal access token.
+func (principal *Principal) IsAPIToken() bool {
+	return principal.APITokenId != ""
+}
+
+// IsImpersonated reports whether an admin makes the request on behalf of the user.
+func (principal *Principal) IsImpersonated() bool {
+	return principal.ImpersonatorId != ""
+}
+
+type principalContextKey struct{}
+
+// ResolvePrincipal verifies a raw PocketBase token and loads the user it belongs to.
+func ResolvePrincipal(pbClient *pocketbase.PocketBaseClient, rawToken string) (*Principal, error) {
+	userId, err := pbClient.VerifyUserToken(rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	userInfo, err := pbClient.ViewUser(userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user %s: %w", userId, err)
+	}
+	if !userInfo.IsActive() {
+		return nil, ErrUserNotActive
+	}
+
+	return &Principal{
+		UserId:    userInfo.Id,
+		RoleId:    userInfo.RoleId,
+		SettingId: userInfo.SettingId,
+		Token:     rawToken,
+		Verified:  userInfo.Verified,
+	}, nil
+}
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored by the auth middleware, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
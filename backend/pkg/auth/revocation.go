@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"alphalabz/pkg/tools"
+	"fmt"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// RevocationList keeps track of signed-out tokens until they would have expired anyway.
+//
+// Entries are keyed by the token's jti claim when present, otherwise by the token hash.
+type RevocationList struct {
+	revoked *cache.Cache
+}
+
+// NewRevocationList creates an empty revocation list.
+func NewRevocationList() *RevocationList {
+	return &RevocationList{revoked: cache.New(cache.NoExpiration, 10*time.Minute)}
+}
+
+// Revoke marks a token as unusable for the rest of its lifetime.
+func (rl *RevocationList) Revoke(rawToken string) error {
+	claims, err := tools.ParsePocketBaseJWT(rawToken)
+	if err != nil {
+		return fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	expiresIn := time.Until(time.Unix(int64(claims.Exp), 0))
+	if expiresIn <= 0 {
+		return nil // Already expired, nothing to revoke
+	}
+
+	rl.revoked.Set(revocationKey(rawToken, claims), true, expiresIn)
+	return nil
+}
+
+// IsRevoked reports whether a token has been revoked.
+func (rl *RevocationList) IsRevoked(rawToken string) bool {
+	claims, err := tools.ParsePocketBaseJWT(rawToken)
+	if err != nil {
+		return false
+	}
+
+	_, found := rl.revoked.Get(revocationKey(rawToken, claims))
+	return found
+}
+
+func revocationKey(rawToken string, claims *tools.PocketBaseJWTPayload) string {
+	if claims.ID != "" {
+		return "jti:" + claims.ID
+	}
+	return "hash:" + tools.HashToken(rawToken)
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// sessionCacheDuration is how long an active session is trusted before PocketBase is asked again.
+const sessionCacheDuration = time.Minute
+
+// maxUserAgentLength keeps oversized User-Agent headers out of the sessions collection.
+const maxUserAgentLength = 512
+
+// SessionRegistry records every login token handed out, so users can see and end their sessions.
+//
+// Login tokens without a session are refused by the auth middleware.
+type SessionRegistry struct {
+	pbClient *pocketbase.PocketBaseClient
+	active   *cache.Cache
+}
+
+// NewSessionRegistry creates a registry backed by the "sessions" collection.
+func NewSessionRegistry(pbClient *pocketbase.PocketBaseClient) *SessionRegistry {
+	return &SessionRegistry{
+		pbClient: pbClient,
+		active:   cache.New(sessionCacheDuration, 10*time.Minute),
+	}
+}
+
+// Register starts a session for a newly issued login token.
+func (sr *SessionRegistry) Register(rawToken string, r *http.Request) (pocketbase.Session, error) {
+	return sr.register(rawToken, r, "")
+}
+
+// RegisterImpersonation starts a session for a token an admin uses to impersonate a user.
+func (sr *SessionRegistry) RegisterImpersonation(rawToken string, r *http.Request, impersonatorId string) (pocketbase.Session, error) {
+	return sr.register(rawToken, r, impersonatorId)
+}
+
+func (sr *SessionRegistry) register(rawToken string, r *http.Request, impersonatorId string) (pocketbase.Session, error) {
+	claims, err := tools.ParsePocketBaseJWT(rawToken)
+	if err != nil {
+		return pocketbase.Session{}, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	session := pocketbase.Session{
+		UserId:    claims.Id,
+		TokenHash: tools.HashToken(rawToken),
+		IP:        tools.ClientIP(r),
+		UserAgent: userAgent,
+		Expires:   time.Unix(int64(claims.Exp), 0).UTC().Format(pocketbase.DateLayout),
+
+		ImpersonatorId: impersonatorId,
+	}
+	if err := sr.pbClient.CreateSession(&session); err != nil {
+		return pocketbase.Session{}, err
+	}
+
+	sr.active.Set(session.TokenHash, session, cache.DefaultExpiration)
+	return session, nil
+}
+
+// IsActive reports whether the login token belongs to a session that was not ended.
+func (sr *SessionRegistry) IsActive(rawToken string) (bool, error) {
+	session, err := sr.Lookup(rawToken)
+	return session.Id != "", err
+}
+
+// Lookup returns the session of a login token.
+//
+// It returns an empty session (without Id) and no error if the token has no session or it was ended.
+func (sr *SessionRegistry) Lookup(rawToken string) (pocketbase.Session, error) {
+	tokenHash := tools.HashToken(rawToken)
+	if session, found := sr.active.Get(tokenHash); found {
+		return session.(pocketbase.Session), nil
+	}
+
+	session, err := sr.pbClient.FindSessionByHash(tokenHash)
+	if err != nil || session.Id == "" {
+		return pocketbase.Session{}, err
+	}
+
+	sr.active.Set(tokenHash, session, cache.DefaultExpiration)
+	return session, nil
+}
+
+// List returns the sessions of a user.
+func (sr *SessionRegistry) List(userId string) ([]pocketbase.Session, error) {
+	return sr.pbClient.ListSessions(userId)
+}
+
+// End ends a session, its token is refused from now on.
+func (sr *SessionRegistry) End(session pocketbase.Session) error {
+	sr.active.Delete(session.TokenHash)
+	return sr.pbClient.DeleteSession(session.Id)
+}
+
+// EndToken ends the session of a login token, if it has one.
+func (sr *SessionRegistry) EndToken(rawToken string) error {
+	tokenHash := tools.HashToken(rawToken)
+	sr.active.Delete(tokenHash)
+
+	session, err := sr.pbClient.FindSessionByHash(tokenHash)
+	if err != nil || session.Id == "" {
+		return err
+	}
+
+	return sr.pbClient.DeleteSession(session.Id)
+}
+
+// EndAll ends every session of a user and returns how many were ended.
+func (sr *SessionRegistry) EndAll(userId string) (int, error) {
+	sessions, err := sr.pbClient.ListSessions(userId)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, session := range sessions {
+		if err := sr.End(session); err != nil {
+			return i, err
+		}
+	}
+
+	return len(sessions), nil
+}
+
+// PurgeExpired removes the sessions whose token expired.
+func (sr *SessionRegistry) PurgeExpired() {
+	sessions, err := sr.pbClient.ListExpiredSessions(time.Now().UTC().Format(pocketbase.DateLayout))
+	if err != nil {
+		log.Println("Failed to list expired sessions:", err)
+		return
+	}
+
+	for _, session := range sessions {
+		if err := sr.End(session); err != nil {
+			log.Println("Failed to remove expired session:", err)
+		}
+	}
+}
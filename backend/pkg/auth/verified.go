@@ -0,0 +1,26 @@
+package auth
+
+import "net/http"
+
+// RequireVerifiedEmail returns a chi middleware that only lets the request through
+// when the principal confirmed their email address.
+//
+// It must be mounted after the auth middleware that stores the principal in the request context.
+func RequireVerifiedEmail() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !principal.Verified {
+				http.Error(w, "Email address not verified", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -8,16 +8,19 @@ import (
 	"net/http"
 	"strings"
 	"sync"
-	"time"
+	"sync/atomic"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
 )
 
 // CasbinEnforcer struct to manage RBAC enforcement
+//
+// Policies are never changed in place: a new enforcer is built on the side and published atomically,
+// so permission checks running during a reload always see a complete set of policies.
 type CasbinEnforcer struct {
-	Enforcer *casbin.Enforcer
-	mu       sync.Mutex // Prevents race conditions when updating policies
+	enforcer atomic.Pointer[casbin.Enforcer]
+	mu       sync.Mutex // Serializes policy updates
 }
 
 type PermissionConfig struct {
@@ -39,31 +42,47 @@ type RolePermission struct {
 	// CollectionName string                 `json:"collectionName"`
 	// Description string                 `json:"description"`
 	Id string `json:"id"`
+	// Parent is the role this role inherits its permissions from, empty for none
+	Parent string `json:"parent"`
 	// Name        string                 `json:"name"`
 	// Type       string                 `json:"type"`
 	Permissions map[string]interface{} `json:"permissions"`
 }
 
-// InitializeCasbin initializes Casbin with provided policies (no file storage)
-func InitializeCasbin(policies [][]interface{}) (*CasbinEnforcer, error) {
-	// Define RBAC model
-	rbacModel := `
-		[request_definition]
-		r = sub, obj, act, scope
+// rbacModel is the Casbin model: a role (sub) inherits the policies of its parents through "g" rules.
+const rbacModel = `
+	[request_definition]
+	r = sub, obj, act, scope
 
-		[policy_definition]
-		p = sub, obj, act, scope
+	[policy_definition]
+	p = sub, obj, act, scope
 
-		[role_definition]
-		g = _, _
+	[role_definition]
+	g = _, _
 
-		[policy_effect]
-		e = some(where (p.eft == allow))
+	[policy_effect]
+	e = some(where (p.eft == allow))
 
-		[matchers]
-		m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act && r.scope == p.scope
-	`
+	[matchers]
+	m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act && r.scope == p.scope
+`
 
+// InitializeCasbin initializes Casbin with provided policies and role inheritance rules (no file storage)
+func InitializeCasbin(policies [][]interface{}, groupingPolicies [][]interface{}) (*CasbinEnforcer, error) {
+	e, err := newEnforcer(policies, groupingPolicies)
+	if err != nil {
+		return nil, err
+	}
+
+	ce := &CasbinEnforcer{}
+	ce.enforcer.Store(e)
+
+	log.Println("Casbin Enforcer initialized successfully.")
+	return ce, nil
+}
+
+// newEnforcer builds an enforcer holding the given policies and role inheritance rules.
+func newEnforcer(policies [][]interface{}, groupingPolicies [][]interface{}) (*casbin.Enforcer, error) {
 	// Create model
 	m, err := model.NewModelFromString(rbacModel)
 	if err != nil {
@@ -76,16 +95,25 @@ func InitializeCasbin(policies [][]interface{}) (*CasbinEnforcer, error) {
 		return nil, fmt.Errorf("failed to create Casbin enforcer: %v", err)
 	}
 
-	// Add initial policies dynamically
 	for _, policy := range policies {
 		_, _ = e.AddPolicy(policy...)
 	}
+	for _, groupingPolicy := range groupingPolicies {
+		_, _ = e.AddGroupingPolicy(groupingPolicy...)
+	}
 
-	log.Println("Casbin Enforcer initialized successfully.")
-	return &CasbinEnforcer{Enforcer: e}, nil
+	return e, nil
 }
 
-// ReloadPolicies fetches the latest policies from PocketBase and updates Casbin
+// current returns the published enforcer. It must not be modified, callers should use a single
+// snapshot for all the checks of one decision.
+func (ce *CasbinEnforcer) current() *casbin.Enforcer {
+	return ce.enforcer.Load()
+}
+
+// ReloadPolicies fetches the latest policies from PocketBase and updates Casbin.
+//
+// Single roles are reloaded as they change (see ReloadRole and WatchRoles), the full reload is a safety net.
 func (ce *CasbinEnforcer) ReloadPolicies(pbClient *pocketbase.PocketBaseClient) error {
 	ce.mu.Lock()
 	defer ce.mu.Unlock()
@@ -93,45 +121,41 @@ func (ce *CasbinEnforcer) ReloadPolicies(pbClient *pocketbase.PocketBaseClient)
 	log.Println("Reloading policies from PocketBase...")
 
 	// Fetch latest policies from PocketBase
-	policies, err := FetchPermissions(pbClient)
+	policies, groupingPolicies, err := FetchPermissions(pbClient)
 	if err != nil {
 		return fmt.Errorf("failed to fetch policies: %v", err)
 	}
 
-	// Clear existing policies
-	ce.Enforcer.ClearPolicy()
+	// Role changes are applied as they happen, so any difference here is a change that was missed
+	currentPolicies, _ := ce.current().GetPolicy()
+	currentGroupingPolicies, _ := ce.current().GetGroupingPolicy()
+	added, removed := policyDrift(currentPolicies, policies)
+	groupingAdded, groupingRemoved := policyDrift(currentGroupingPolicies, groupingPolicies)
+	if added+removed+groupingAdded+groupingRemoved > 0 {
+		log.Printf("Casbin policy drift detected: %d policies added, %d removed, %d inheritance rules added, %d removed",
+			added, removed, groupingAdded, groupingRemoved)
+	}
 
-	// Add the latest policies
-	for _, policy := range policies {
-		_, _ = ce.Enforcer.AddPolicy(policy...)
+	e, err := newEnforcer(policies, groupingPolicies)
+	if err != nil {
+		return err
 	}
+	ce.enforcer.Store(e)
 
 	log.Println("Casbin policies reloaded successfully.")
 	return nil
 }
 
-// StartPolicyAutoReload starts a background goroutine that periodically reloads policies
-func (ce *CasbinEnforcer) StartPolicyAutoReload(pbClient *pocketbase.PocketBaseClient, interval time.Duration) {
-	go func() {
-		for {
-			time.Sleep(interval)
-			err := ce.ReloadPolicies(pbClient)
-			if err != nil {
-				fmt.Println("Error reloading policies:", err)
-			}
-		}
-	}()
-}
-
-// FetchPermissions fetch the latest permissons settings from the database and save as a local csv
-func FetchPermissions(pbClient *pocketbase.PocketBaseClient) ([][]interface{}, error) {
+// FetchPermissions fetch the latest permissons settings from the database.
+// It returns the permission policies and the role inheritance ("g") rules.
+func FetchPermissions(pbClient *pocketbase.PocketBaseClient) ([][]interface{}, [][]interface{}, error) {
 	url := fmt.Sprintf("%s/api/collections/roles/records?perPage=99", pbClient.BaseURL)
 
 	// Construct request
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		fmt.Println("Error creating request:", err)
-		return nil, err
+		return nil, nil, err
 	}
 	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
 
@@ -140,12 +164,12 @@ func FetchPermissions(pbClient *pocketbase.PocketBaseClient) ([][]interface{}, e
 	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Println("Error sending request:", err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
+		return nil, nil, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
 	}
 
 	// Parse response
@@ -153,11 +177,11 @@ func FetchPermissions(pbClient *pocketbase.PocketBaseClient) ([][]interface{}, e
 	err = json.NewDecoder(resp.Body).Decode(&respData)
 	if err != nil {
 		fmt.Println("Error decoding response:", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Convert to Casbin policies ([][]interface{})
-	return convertCasbinFormat(respData.Items), nil
+	return convertCasbinFormat(respData.Items), convertInheritance(respData.Items), nil
 }
 
 // ConvertCasbinFormat converts RolePermissions into Casbin policy rules
@@ -168,6 +192,7 @@ func convertCasbinFormat(permissions []RolePermission) [][]interface{} {
 		roleID := role.Id // Role ID as Casbin "sub"
 
 		for resource, actions := range role.Permissions {
+			// Non-list entries (e.g. "mfa_required") are role options, not permissions
 			actionList, ok := actions.([]interface{})
 			if !ok {
 				continue
@@ -179,25 +204,21 @@ func convertCasbinFormat(permissions []RolePermission) [][]interface{} {
 					continue
 				}
 
-				actionParts := strings.Split(actionStr, ":") // Split action & scope
-				actionType := actionParts[0]                 // "view", "update", etc.
-				scope := "all"                               // Default scope
-
-				if len(actionParts) > 1 {
-					scopes := strings.Split(actionParts[1], ",") // Handle multiple scopes
-
-					for _, s := range scopes {
-						casbinPolicies = append(casbinPolicies, []interface{}{
-							roleID, resource, actionType, strings.TrimSpace(s),
-						})
-					}
+				// "action:scope,scope", entries without a scope grant nothing (Catalog.Validate rejects them)
+				actionType, scopes, found := strings.Cut(actionStr, ":")
+				if !found {
+					log.Printf("Role %s: %s entry %q has no scope, ignoring", roleID, resource, actionStr)
 					continue
 				}
 
-				// If there's only one scope, add normally
-				casbinPolicies = append(casbinPolicies, []interface{}{
-					roleID, resource, actionType, scope,
-				})
+				for _, s := range strings.Split(scopes, ",") {
+					if s = strings.TrimSpace(s); s == "" {
+						continue
+					}
+					casbinPolicies = append(casbinPolicies, []interface{}{
+						roleID, resource, actionType, s,
+					})
+				}
 			}
 		}
 	}
@@ -0,0 +1,172 @@
+package casbin
+
+import (
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RoleIdScope stands in the catalog for the ID of any existing role, e.g. "create:<role id>" on "users"
+// lets a role invite users into that role.
+const RoleIdScope = "{role_id}"
+
+// Catalog lists the permissions a role document can grant: the actions of each resource and the scopes
+// each action accepts, and the role options (non-list entries such as "mfa_required").
+type Catalog struct {
+	Resources map[string]map[string][]string `json:"resources"`
+	Options   []string                       `json:"options"`
+}
+
+// checkedPermissions are the permissions the backend checks, registered as the routes are set up
+// (see RegisterPermission). They are added to the permissions of defaultPermission.json to build the catalog.
+var checkedPermissions = struct {
+	sync.Mutex
+	entries map[string][]string
+}{entries: make(map[string][]string)}
+
+// RegisterPermission records that the backend checks the "action:scope" entry on a resource, so that
+// role documents may grant it. auth.RequirePermission and auth.RequireRecordPermission register theirs,
+// permissions checked inside handlers are registered next to their routes.
+func RegisterPermission(resource, entry string) {
+	checkedPermissions.Lock()
+	defer checkedPermissions.Unlock()
+
+	if !tools.Contains(checkedPermissions.entries[resource], entry) {
+		checkedPermissions.entries[resource] = append(checkedPermissions.entries[resource], entry)
+	}
+}
+
+// roleOptions are the boolean role options a permissions document can hold next to the resources.
+var roleOptions = []string{"mfa_required"}
+
+// LoadCatalog builds the catalog from a permissions document (defaultPermission.json) and the registered
+// permissions, so it must be called once the routes are set up.
+// The "*" scope (any record) is accepted by every action.
+func LoadCatalog(filepath string) (*Catalog, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var defaults map[string][]string
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filepath, err)
+	}
+
+	catalog := &Catalog{Resources: make(map[string]map[string][]string), Options: roleOptions}
+	for resource, actions := range defaults {
+		for _, action := range actions {
+			catalog.add(resource, action)
+		}
+	}
+
+	checkedPermissions.Lock()
+	for resource, actions := range checkedPermissions.entries {
+		for _, action := range actions {
+			catalog.add(resource, action)
+		}
+	}
+	checkedPermissions.Unlock()
+
+	for _, actions := range catalog.Resources {
+		for action, scopes := range actions {
+			sort.Strings(scopes)
+			actions[action] = scopes
+		}
+	}
+
+	return catalog, nil
+}
+
+// add adds an "action:scope,scope" entry of a resource, with the "*" scope.
+func (c *Catalog) add(resource, entry string) {
+	if c.Resources[resource] == nil {
+		c.Resources[resource] = make(map[string][]string)
+	}
+
+	action, scopes, _ := strings.Cut(entry, ":")
+	known := c.Resources[resource][action]
+	for _, scope := range append(strings.Split(scopes, ","), "*") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || tools.Contains(known, scope) {
+			continue
+		}
+		known = append(known, scope)
+	}
+	c.Resources[resource][action] = known
+}
+
+// Validate checks a role permissions document against the catalog and returns every problem found,
+// or nil if it is valid. roleIds are the existing roles, accepted where the catalog holds RoleIdScope.
+func (c *Catalog) Validate(document interface{}, roleIds []string) []string {
+	permissions, ok := document.(map[string]interface{})
+	if !ok {
+		return []string{"permissions must be an object of resources"}
+	}
+
+	keys := make([]string, 0, len(permissions))
+	for key := range permissions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		if tools.Contains(c.Options, key) {
+			if _, ok := permissions[key].(bool); !ok {
+				problems = append(problems, fmt.Sprintf("%s: option must be true or false", key))
+			}
+			continue
+		}
+
+		actions, ok := c.Resources[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown resource", key))
+			continue
+		}
+
+		entries, ok := permissions[key].([]interface{})
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: must be a list of \"action:scope\" entries", key))
+			continue
+		}
+
+		for _, value := range entries {
+			entry, ok := value.(string)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: entry %v is not a string", key, value))
+				continue
+			}
+
+			// Entries without a scope grant nothing, convertCasbinFormat ignores them
+			action, scopes, found := strings.Cut(entry, ":")
+			allowed, ok := actions[action]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown action %q", key, action))
+				continue
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("%s: %q has no scope", key, entry))
+				continue
+			}
+
+			for _, scope := range strings.Split(scopes, ",") {
+				scope = strings.TrimSpace(scope)
+				switch {
+				case scope == "":
+					problems = append(problems, fmt.Sprintf("%s: %q has an empty scope", key, entry))
+				case scope != RoleIdScope && tools.Contains(allowed, scope):
+				case tools.Contains(allowed, RoleIdScope) && tools.Contains(roleIds, scope):
+				default:
+					problems = append(problems, fmt.Sprintf("%s: unknown scope %q for action %q", key, scope, action))
+				}
+			}
+		}
+	}
+
+	return problems
+}
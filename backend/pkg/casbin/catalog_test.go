@@ -0,0 +1,181 @@
+package casbin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestCatalog builds a catalog from a small defaults document and the registered permissions.
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "defaultPermission.json")
+	defaults := `{"lab_books": ["view:own,shared", "create:own"], "users": ["view:own"]}`
+	if err := os.WriteFile(path, []byte(defaults), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterPermission("lab_books", "update:share,review")
+	RegisterPermission("users", "create:"+RoleIdScope)
+	RegisterPermission("users", "delete:*")
+
+	catalog, err := LoadCatalog(path)
+	if err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+	return catalog
+}
+
+func TestLoadCatalog(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	want := map[string]map[string][]string{
+		"lab_books": {
+			"view":   {"*", "own", "shared"},
+			"create": {"*", "own"},
+			"update": {"*", "review", "share"},
+		},
+		"users": {
+			"view":   {"*", "own"},
+			"create": {"*", RoleIdScope},
+			"delete": {"*"},
+		},
+	}
+	if !reflect.DeepEqual(catalog.Resources, want) {
+		t.Errorf("LoadCatalog() resources = %v, want %v", catalog.Resources, want)
+	}
+	if !reflect.DeepEqual(catalog.Options, []string{"mfa_required"}) {
+		t.Errorf("LoadCatalog() options = %v", catalog.Options)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	catalog := newTestCatalog(t)
+	roleIds := []string{"0001", "0003"}
+
+	tests := []struct {
+		name     string
+		document interface{}
+		want     []string
+	}{
+		{
+			name: "valid",
+			document: map[string]interface{}{
+				"lab_books":    []interface{}{"view:own, shared", "update:*"},
+				"users":        []interface{}{"create:0003", "delete:*"},
+				"mfa_required": true,
+			},
+		},
+		{
+			name:     "not an object",
+			document: []interface{}{"lab_books:view:own"},
+			want:     []string{"permissions must be an object of resources"},
+		},
+		{
+			name:     "unknown resource",
+			document: map[string]interface{}{"grades": []interface{}{"view:own"}},
+			want:     []string{"grades: unknown resource"},
+		},
+		{
+			name:     "resource not a list",
+			document: map[string]interface{}{"lab_books": "view:own"},
+			want:     []string{`lab_books: must be a list of "action:scope" entries`},
+		},
+		{
+			name:     "entry not a string",
+			document: map[string]interface{}{"lab_books": []interface{}{42.0}},
+			want:     []string{"lab_books: entry 42 is not a string"},
+		},
+		{
+			name:     "unknown action",
+			document: map[string]interface{}{"lab_books": []interface{}{"publish:own"}},
+			want:     []string{`lab_books: unknown action "publish"`},
+		},
+		{
+			name:     "no scope",
+			document: map[string]interface{}{"users": []interface{}{"delete"}},
+			want:     []string{`users: "delete" has no scope`},
+		},
+		{
+			name:     "empty scope",
+			document: map[string]interface{}{"lab_books": []interface{}{"view:own,"}},
+			want:     []string{`lab_books: "view:own," has an empty scope`},
+		},
+		{
+			name:     "unknown scope",
+			document: map[string]interface{}{"lab_books": []interface{}{"view:all"}},
+			want:     []string{`lab_books: unknown scope "all" for action "view"`},
+		},
+		{
+			name:     "role ID where the catalog has no role scope",
+			document: map[string]interface{}{"lab_books": []interface{}{"view:0003"}},
+			want:     []string{`lab_books: unknown scope "0003" for action "view"`},
+		},
+		{
+			name:     "unknown role ID",
+			document: map[string]interface{}{"users": []interface{}{"create:9999"}},
+			want:     []string{`users: unknown scope "9999" for action "create"`},
+		},
+		{
+			name:     "role scope placeholder",
+			document: map[string]interface{}{"users": []interface{}{"create:" + RoleIdScope}},
+			want:     []string{`users: unknown scope "{role_id}" for action "create"`},
+		},
+		{
+			name:     "option not a boolean",
+			document: map[string]interface{}{"mfa_required": "yes"},
+			want:     []string{"mfa_required: option must be true or false"},
+		},
+		{
+			name: "every problem, in resource order",
+			document: map[string]interface{}{
+				"users":     []interface{}{"delete"},
+				"lab_books": []interface{}{"view:all", "publish:own"},
+			},
+			want: []string{
+				`lab_books: unknown scope "all" for action "view"`,
+				`lab_books: unknown action "publish"`,
+				`users: "delete" has no scope`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := catalog.Validate(tt.document, roleIds); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateAgreesWithLoader checks that the entries Validate rejects for their scopes are the ones
+// convertCasbinFormat grants nothing for.
+func TestValidateAgreesWithLoader(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	tests := []struct {
+		entry string
+		valid bool
+		want  [][]interface{}
+	}{
+		{"delete:*", true, [][]interface{}{{"0001", "users", "delete", "*"}}},
+		{"create:0003", true, [][]interface{}{{"0001", "users", "create", "0003"}}},
+		{"view:own, *", true, [][]interface{}{{"0001", "users", "view", "own"}, {"0001", "users", "view", "*"}}},
+		{"delete", false, nil},
+		{"delete:", false, nil},
+	}
+
+	for _, tt := range tests {
+		document := map[string]interface{}{"users": []interface{}{tt.entry}}
+
+		if problems := catalog.Validate(document, []string{"0001", "0003"}); (len(problems) == 0) != tt.valid {
+			t.Errorf("Validate(%q) = %q, want valid = %v", tt.entry, problems, tt.valid)
+		}
+		if got := convertCasbinFormat([]RolePermission{{Id: "0001", Permissions: document}}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("convertCasbinFormat(%q) = %v, want %v", tt.entry, got, tt.want)
+		}
+	}
+}
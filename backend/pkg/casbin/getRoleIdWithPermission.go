@@ -1,14 +1,15 @@
 package casbin
 
-// GetRoleIDsByPermission returns a list of role IDs that have the specified permission
+import "alphalabz/pkg/tools"
+
+// GetRoleIDsByPermission returns a list of role IDs that have the specified permission, directly or by inheritance
 func (ce *CasbinEnforcer) GetRoleIDsByPermission(resource, action, scope string) ([]string, error) {
-	ce.mu.Lock()
-	defer ce.mu.Unlock()
+	e := ce.current()
 
 	var roleIDs []string
 
 	// Get all policies
-	policies, err := ce.Enforcer.GetPolicy()
+	policies, err := e.GetPolicy()
 	if err != nil {
 		return nil, err
 	}
@@ -22,7 +23,12 @@ func (ce *CasbinEnforcer) GetRoleIDsByPermission(resource, action, scope string)
 		roleID, obj, act, scp := policy[0], policy[1], policy[2], policy[3]
 
 		if obj == resource && act == action && (scope == "" || scp == scope) {
-			roleIDs = append(roleIDs, roleID)
+			// Roles inheriting from this role have the permission too
+			for _, inheritingRoleID := range inheritingRoles(e, roleID) {
+				if !tools.Contains(roleIDs, inheritingRoleID) {
+					roleIDs = append(roleIDs, inheritingRoleID)
+				}
+			}
 		}
 	}
 
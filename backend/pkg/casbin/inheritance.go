@@ -0,0 +1,100 @@
+package casbin
+
+import (
+	"alphalabz/pkg/tools"
+	"log"
+	"sort"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// AdminRoleId is the ID of the admin role.
+const AdminRoleId = "0001"
+
+// convertInheritance converts the parent of each role into Casbin "g" rules: [roleId, parentId].
+//
+// Parents that do not exist are ignored, and so is any parent link that would close a cycle
+// (e.g. "TA inherits Student" and "Student inherits TA"), so that loading never loops.
+func convertInheritance(roles []RolePermission) [][]interface{} {
+	exists := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		exists[role.Id] = true
+	}
+
+	// Links are accepted in role ID order, so the same link is dropped on every load
+	sorted := make([]RolePermission, len(roles))
+	copy(sorted, roles)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	parents := make(map[string]string, len(roles))
+	var groupingPolicies [][]interface{}
+	for _, role := range sorted {
+		if role.Parent == "" {
+			continue
+		}
+		if !exists[role.Parent] {
+			log.Printf("Role %s inherits from unknown role %s, ignoring", role.Id, role.Parent)
+			continue
+		}
+		if inherits(parents, role.Parent, role.Id) {
+			log.Printf("Role %s inheriting from %s creates a cycle, ignoring", role.Id, role.Parent)
+			continue
+		}
+
+		parents[role.Id] = role.Parent
+		groupingPolicies = append(groupingPolicies, []interface{}{role.Id, role.Parent})
+	}
+
+	return groupingPolicies
+}
+
+// inherits reports whether roleId is ancestorId or inherits from it through the accepted parent links.
+func inherits(parents map[string]string, roleId, ancestorId string) bool {
+	for roleId != "" {
+		if roleId == ancestorId {
+			return true
+		}
+		roleId = parents[roleId]
+	}
+	return false
+}
+
+// effectiveRoles returns the role and every role it inherits from.
+func effectiveRoles(e *casbin.Enforcer, roleId string) []string {
+	roles := []string{roleId}
+
+	inherited, err := e.GetImplicitRolesForUser(roleId)
+	if err != nil {
+		log.Printf("Failed to get the roles inherited by %s: %v", roleId, err)
+		return roles
+	}
+
+	return append(roles, inherited...)
+}
+
+// inheritingRoles returns the role and every role inheriting from it.
+func inheritingRoles(e *casbin.Enforcer, roleId string) []string {
+	roles := []string{roleId}
+
+	inheriting, err := e.GetImplicitUsersForRole(roleId)
+	if err != nil {
+		log.Printf("Failed to get the roles inheriting from %s: %v", roleId, err)
+		return roles
+	}
+
+	return append(roles, inheriting...)
+}
+
+// IsAdminRole reports whether a role is the admin role or inherits from it, and so has every admin permission.
+func (ce *CasbinEnforcer) IsAdminRole(roleId string) bool {
+	if roleId == AdminRoleId {
+		return true
+	}
+
+	e := ce.current()
+	if e == nil {
+		return false
+	}
+
+	return tools.Contains(effectiveRoles(e, roleId), AdminRoleId)
+}
@@ -0,0 +1,171 @@
+package casbin
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// role returns a role with a single "labbook: view:own" permission, inheriting from parent.
+func role(id, parent string) RolePermission {
+	return RolePermission{Id: id, Parent: parent, Permissions: map[string]interface{}{
+		"labbook": []interface{}{"view:own"},
+	}}
+}
+
+func TestConvertInheritance(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []RolePermission
+		want  [][]interface{}
+	}{
+		{
+			name:  "no parent",
+			roles: []RolePermission{role("A", ""), role("B", "")},
+			want:  nil,
+		},
+		{
+			name:  "three-level chain",
+			roles: []RolePermission{role("C", "B"), role("A", ""), role("B", "A")},
+			want:  [][]interface{}{{"B", "A"}, {"C", "B"}},
+		},
+		{
+			// Links are accepted in role ID order: A -> B is kept, B -> A closes the cycle
+			name:  "cycle",
+			roles: []RolePermission{role("B", "A"), role("A", "B")},
+			want:  [][]interface{}{{"A", "B"}},
+		},
+		{
+			name:  "three-role cycle",
+			roles: []RolePermission{role("A", "C"), role("B", "A"), role("C", "B")},
+			want:  [][]interface{}{{"A", "C"}, {"B", "A"}},
+		},
+		{
+			name:  "role inheriting from itself",
+			roles: []RolePermission{role("A", "A")},
+			want:  nil,
+		},
+		{
+			name:  "missing parent",
+			roles: []RolePermission{role("A", "deleted"), role("B", "A")},
+			want:  [][]interface{}{{"B", "A"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertInheritance(tt.roles); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertInheritance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectiveRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []RolePermission
+		want  map[string][]string
+	}{
+		{
+			name:  "three-level chain",
+			roles: []RolePermission{role("A", ""), role("B", "A"), role("C", "B")},
+			want:  map[string][]string{"A": {"A"}, "B": {"A", "B"}, "C": {"A", "B", "C"}},
+		},
+		{
+			name:  "cycle",
+			roles: []RolePermission{role("A", "B"), role("B", "A")},
+			want:  map[string][]string{"A": {"A", "B"}, "B": {"B"}},
+		},
+		{
+			name:  "missing parent",
+			roles: []RolePermission{role("A", "deleted")},
+			want:  map[string][]string{"A": {"A"}, "deleted": {"deleted"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := newEnforcer(convertCasbinFormat(tt.roles), convertInheritance(tt.roles))
+			if err != nil {
+				t.Fatalf("newEnforcer() error = %v", err)
+			}
+
+			for roleId, want := range tt.want {
+				got := effectiveRoles(e, roleId)
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("effectiveRoles(%s) = %v, want %v", roleId, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInheritedPermissions(t *testing.T) {
+	roles := []RolePermission{
+		{Id: "student", Permissions: map[string]interface{}{"labbook": []interface{}{"view:own"}}},
+		{Id: "ta", Parent: "student", Permissions: map[string]interface{}{"labbook": []interface{}{"view:shared"}}},
+		{Id: "teacher", Parent: "ta", Permissions: map[string]interface{}{"labbook": []interface{}{"update:*"}}},
+	}
+
+	ce, err := InitializeCasbin(convertCasbinFormat(roles), convertInheritance(roles))
+	if err != nil {
+		t.Fatalf("InitializeCasbin() error = %v", err)
+	}
+
+	tests := []struct {
+		roleId string
+		action string
+		scope  string
+		want   bool
+	}{
+		{"teacher", "view", "own", true},
+		{"teacher", "view", "shared", true},
+		{"teacher", "update", "*", true},
+		{"ta", "view", "own", true},
+		{"ta", "update", "*", false},
+		{"student", "view", "shared", false},
+	}
+
+	for _, tt := range tests {
+		got, _, err := ce.VerifyRolePermission(tt.roleId, PermissionConfig{Resources: "labbook", Actions: tt.action, Scopes: tt.scope})
+		if err != nil {
+			t.Fatalf("VerifyRolePermission() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("%s %s:%s = %v, want %v", tt.roleId, tt.action, tt.scope, got, tt.want)
+		}
+	}
+
+	scopes, err := ce.checkPermissionScopes("teacher", "labbook", "view")
+	sort.Strings(scopes)
+	if err != nil || !reflect.DeepEqual(scopes, []string{"own", "shared"}) {
+		t.Errorf("checkPermissionScopes(teacher) = %v, %v, want [own shared]", scopes, err)
+	}
+}
+
+func TestIsAdminRole(t *testing.T) {
+	roles := []RolePermission{
+		role(AdminRoleId, ""),
+		role("deputy", AdminRoleId),
+		role("assistant", "deputy"),
+		role("0003", ""),
+	}
+	ce, err := InitializeCasbin(convertCasbinFormat(roles), convertInheritance(roles))
+	if err != nil {
+		t.Fatalf("InitializeCasbin() error = %v", err)
+	}
+
+	for roleId, want := range map[string]bool{
+		AdminRoleId: true,
+		"deputy":    true,
+		"assistant": true,
+		"0003":      false,
+		"unknown":   false,
+	} {
+		if got := ce.IsAdminRole(roleId); got != want {
+			t.Errorf("IsAdminRole(%s) = %v, want %v", roleId, got, want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package casbin
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	watchRetryMin = 5 * time.Second
+	watchRetryMax = time.Minute
+)
+
+// ReloadRole reloads the policies and parent of a single role from PocketBase, right after it changed.
+// A role that no longer exists is removed.
+func (ce *CasbinEnforcer) ReloadRole(pbClient *pocketbase.PocketBaseClient, roleId string) error {
+	role, found, err := fetchRolePermission(pbClient, roleId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch role %s: %v", roleId, err)
+	}
+
+	if !found {
+		ce.RemoveRole(roleId)
+		return nil
+	}
+
+	ce.applyRole(role)
+	return nil
+}
+
+// RemoveRole drops the policies of a deleted role, and the inheritance links from and to it.
+func (ce *CasbinEnforcer) RemoveRole(roleId string) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	current := ce.current()
+	policies, _ := current.GetPolicy()
+	groupingPolicies, _ := current.GetGroupingPolicy()
+
+	e, err := newEnforcer(rulesWithout(policies, roleId, 0), rulesWithout(groupingPolicies, roleId, 0, 1))
+	if err != nil {
+		log.Printf("Failed to remove the policies of role %s: %v", roleId, err)
+		return
+	}
+	ce.enforcer.Store(e)
+
+	log.Printf("Casbin policies of role %s removed.", roleId)
+}
+
+// applyRole replaces the policies and parent of a role.
+func (ce *CasbinEnforcer) applyRole(role RolePermission) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	current := ce.current()
+	policies, _ := current.GetPolicy()
+	groupingPolicies, _ := current.GetGroupingPolicy()
+
+	e, err := newEnforcer(
+		append(rulesWithout(policies, role.Id, 0), convertCasbinFormat([]RolePermission{role})...),
+		rulesWithout(groupingPolicies, role.Id, 0),
+	)
+	if err != nil {
+		log.Printf("Failed to reload the policies of role %s: %v", role.Id, err)
+		return
+	}
+
+	// The new enforcer is not published yet, so it can still be changed
+	if role.Parent != "" {
+		if role.Parent == role.Id || tools.Contains(effectiveRoles(e, role.Parent), role.Id) {
+			log.Printf("Role %s inheriting from %s creates a cycle, ignoring", role.Id, role.Parent)
+		} else {
+			_, _ = e.AddGroupingPolicy(role.Id, role.Parent)
+		}
+	}
+	ce.enforcer.Store(e)
+
+	log.Printf("Casbin policies of role %s reloaded.", role.Id)
+}
+
+// rulesWithout returns the rules that do not hold roleId at any of the given positions.
+func rulesWithout(rules [][]string, roleId string, positions ...int) [][]interface{} {
+	var kept [][]interface{}
+
+	for _, rule := range rules {
+		matches := false
+		for _, position := range positions {
+			if position < len(rule) && rule[position] == roleId {
+				matches = true
+				break
+			}
+		}
+		if matches {
+			continue
+		}
+
+		values := make([]interface{}, len(rule))
+		for i, value := range rule {
+			values[i] = value
+		}
+		kept = append(kept, values)
+	}
+
+	return kept
+}
+
+// WatchRoles subscribes to the changes of the roles collection in PocketBase, including the ones made
+// in the admin UI, and reloads the policies of each changed role. It reconnects until ctx is canceled.
+func (ce *CasbinEnforcer) WatchRoles(ctx context.Context, pbClient *pocketbase.PocketBaseClient) {
+	go func() {
+		retry := watchRetryMin
+		for {
+			connected := time.Now()
+			err := pbClient.SubscribeRealtime(ctx, []string{"roles"}, func(_ string, event pocketbase.RealtimeEvent) {
+				var role RolePermission
+				if err := json.Unmarshal(event.Record, &role); err != nil || role.Id == "" {
+					log.Println("Failed to decode role change:", err)
+					return
+				}
+
+				if event.Action == "delete" {
+					ce.RemoveRole(role.Id)
+				} else {
+					ce.applyRole(role)
+				}
+			})
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Changes made while disconnected are caught by the periodic full reload
+			if time.Since(connected) > watchRetryMax {
+				retry = watchRetryMin
+			}
+			log.Printf("Role changes subscription stopped, retrying in %s: %v", retry, err)
+			time.Sleep(retry)
+			if retry *= 2; retry > watchRetryMax {
+				retry = watchRetryMax
+			}
+		}
+	}()
+}
+
+// fetchRolePermission fetches the permissions document and parent of a role.
+func fetchRolePermission(pbClient *pocketbase.PocketBaseClient, roleId string) (role RolePermission, found bool, err error) {
+	url := fmt.Sprintf("%s/api/collections/roles/records/%s?fields=id,parent,permissions", pbClient.BaseURL, roleId)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return role, false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return role, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return role, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return role, false, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&role); err != nil {
+		return role, false, err
+	}
+
+	return role, true, nil
+}
+
+// policyDrift counts the rules of fetched missing from current (added) and of current missing from fetched (removed).
+func policyDrift(current [][]string, fetched [][]interface{}) (added, removed int) {
+	currentSet := make(map[string]bool, len(current))
+	for _, rule := range current {
+		currentSet[fmt.Sprint(rule)] = true
+	}
+
+	fetchedSet := make(map[string]bool, len(fetched))
+	for _, rule := range fetched {
+		key := fmt.Sprint(rule)
+		fetchedSet[key] = true
+		if !currentSet[key] {
+			added++
+		}
+	}
+
+	for key := range currentSet {
+		if !fetchedSet[key] {
+			removed++
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,181 @@
+package casbin
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// rolesStub is a PocketBase stub serving the roles collection, whose roles the tests change on the fly.
+type rolesStub struct {
+	mu    sync.Mutex
+	roles map[string]RolePermission
+}
+
+func (stub *rolesStub) set(role RolePermission) {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	stub.roles[role.Id] = role
+}
+
+func (stub *rolesStub) remove(roleId string) {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	delete(stub.roles, roleId)
+}
+
+func (stub *rolesStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+
+	roleId, single := strings.CutPrefix(r.URL.Path, "/api/collections/roles/records/")
+	switch {
+	case single:
+		role, ok := stub.roles[roleId]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(role)
+	case r.URL.Path == "/api/collections/roles/records":
+		var items []RolePermission
+		for _, role := range stub.roles {
+			items = append(items, role)
+		}
+		json.NewEncoder(w).Encode(PermissionRespond{Items: items, Page: 1, PerPage: 99, TotalItems: len(items), TotalPages: 1})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newReloadTest returns an enforcer loaded from a roles stub holding student <- ta <- teacher.
+func newReloadTest(t *testing.T) (*CasbinEnforcer, *rolesStub, *pocketbase.PocketBaseClient) {
+	t.Helper()
+
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	stub := &rolesStub{roles: map[string]RolePermission{}}
+	stub.set(RolePermission{Id: "student", Permissions: map[string]interface{}{"labbook": []interface{}{"view:own"}}})
+	stub.set(RolePermission{Id: "ta", Parent: "student", Permissions: map[string]interface{}{"labbook": []interface{}{"view:shared"}}})
+	stub.set(RolePermission{Id: "teacher", Parent: "ta", Permissions: map[string]interface{}{"labbook": []interface{}{"update:*"}}})
+
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	pbClient := &pocketbase.PocketBaseClient{BaseURL: server.URL, HTTPClient: server.Client()}
+
+	policies, groupingPolicies, err := FetchPermissions(pbClient)
+	if err != nil {
+		t.Fatalf("FetchPermissions() error = %v", err)
+	}
+	ce, err := InitializeCasbin(policies, groupingPolicies)
+	if err != nil {
+		t.Fatalf("InitializeCasbin() error = %v", err)
+	}
+
+	return ce, stub, pbClient
+}
+
+// TestReloadWhileEnforcing changes roles and reloads them while permission checks are running.
+// Checks must always see a complete set of policies, never one being rebuilt. Run with -race.
+func TestReloadWhileEnforcing(t *testing.T) {
+	ce, stub, pbClient := newReloadTest(t)
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					// Leaves room for the reloads when there are few CPUs
+					runtime.Gosched()
+				}
+
+				// Permissions that no change below touches
+				allowed, _, err := ce.VerifyRolePermission("teacher", PermissionConfig{Resources: "labbook", Actions: "view", Scopes: "own"})
+				if err != nil || !allowed {
+					t.Errorf("teacher view:own = %v, %v during a reload", allowed, err)
+					return
+				}
+				scopes, err := ce.checkPermissionScopes("teacher", "labbook", "update")
+				if err != nil || !tools.Contains(scopes, "*") {
+					t.Errorf("teacher update scopes = %v, %v during a reload", scopes, err)
+					return
+				}
+				roleIds, err := ce.GetRoleIDsByPermission("labbook", "view", "own")
+				if err != nil || len(roleIds) < 3 {
+					t.Errorf("roles with view:own = %v, %v during a reload", roleIds, err)
+					return
+				}
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	writers.Add(2)
+	go func() {
+		defer writers.Done()
+		for i := 0; i < 20; i++ {
+			ta := RolePermission{Id: "ta", Parent: "student", Permissions: map[string]interface{}{"labbook": []interface{}{"view:shared"}}}
+			if i%2 == 0 {
+				ta.Permissions = map[string]interface{}{"labbook": []interface{}{"view:shared", "update:shared"}}
+				stub.set(RolePermission{Id: "guest", Permissions: map[string]interface{}{"labbook": []interface{}{"view:shared"}}})
+			} else {
+				stub.remove("guest")
+			}
+			stub.set(ta)
+
+			if err := ce.ReloadRole(pbClient, "ta"); err != nil {
+				t.Errorf("ReloadRole(ta) error = %v", err)
+			}
+			if err := ce.ReloadRole(pbClient, "guest"); err != nil {
+				t.Errorf("ReloadRole(guest) error = %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer writers.Done()
+		for i := 0; i < 10; i++ {
+			if err := ce.ReloadPolicies(pbClient); err != nil {
+				t.Errorf("ReloadPolicies() error = %v", err)
+			}
+		}
+	}()
+
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	// The last state of the roles wins: ta without update:shared, no guest
+	if err := ce.ReloadPolicies(pbClient); err != nil {
+		t.Fatalf("ReloadPolicies() error = %v", err)
+	}
+	for _, tt := range []struct {
+		roleId string
+		action string
+		want   bool
+	}{
+		{"ta", "update", false},
+		{"teacher", "view", true},
+		{"guest", "view", false},
+	} {
+		got, _, err := ce.VerifyRolePermission(tt.roleId, PermissionConfig{Resources: "labbook", Actions: tt.action, Scopes: "shared"})
+		if err != nil || got != tt.want {
+			t.Errorf("%s %s:shared = %v, %v, want %v", tt.roleId, tt.action, got, err, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package casbin
 
 import (
 	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
 	"fmt"
 )
 
@@ -22,25 +23,29 @@ func (ce *CasbinEnforcer) ScopeFetcher(pbClient *pocketbase.PocketBaseClient, us
 	return scopes, nil
 }
 
-// checkPermissionScopes retrieves all scopes a user has for a given resource and action
+// checkPermissionScopes retrieves all scopes a role has for a given resource and action, inherited ones included
 func (ce *CasbinEnforcer) checkPermissionScopes(roleId, resource, action string) ([]string, error) {
-	if ce.Enforcer == nil {
+	e := ce.current()
+	if e == nil {
 		return nil, fmt.Errorf("casbin Enforcer is not initialized")
 	}
 
 	// Retrieve all policies in Casbin
-	allPolicies, err := ce.Enforcer.GetPolicy()
+	allPolicies, err := e.GetPolicy()
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve policies: %v", err)
 	}
 
+	// The role also has the scopes of the roles it inherits from
+	roles := effectiveRoles(e, roleId)
+
 	// Store valid scopes for this user/resource/action
 	var scopes []string
 
 	// Iterate through all Casbin policies
 	for _, policy := range allPolicies {
 		// Policy format: [roleId, resource, action, scope]
-		if len(policy) == 4 && policy[0] == roleId && policy[1] == resource && policy[2] == action {
+		if len(policy) == 4 && tools.Contains(roles, policy[0]) && policy[1] == resource && policy[2] == action && !tools.Contains(scopes, policy[3]) {
 			scopes = append(scopes, policy[3]) // Collect the allowed scopes
 		}
 	}
@@ -2,36 +2,28 @@ package casbin
 
 import (
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"fmt"
 )
 
-// CheckPermission validates user actions using Casbin
+// VerifyRolePermission validates a role's actions using Casbin
 //
 // Require Resources, Actions and Scopes to be provided in PermissionConfig
-func (ce *CasbinEnforcer) VerifyJWTPermission(pbClient *pocketbase.PocketBaseClient, rawJwtToken string, permissionConfig PermissionConfig) (reqPermission bool, starPermission bool, err error) {
-	if ce.Enforcer == nil {
+func (ce *CasbinEnforcer) VerifyRolePermission(roleId string, permissionConfig PermissionConfig) (reqPermission bool, starPermission bool, err error) {
+	// Both checks use the same snapshot, even if the policies are reloaded in between
+	e := ce.current()
+	if e == nil {
 		return false, false, fmt.Errorf("casbin Enforcer is not initialized")
 	}
 
-	userId, err := tools.GetUserIdFromJWT(rawJwtToken)
-	if err != nil {
-		return false, false, nil
-	}
-	userRole, err := pbClient.ViewUser(userId)
-	if err != nil {
-		return false, false, nil
-	}
-
-	// Check if the user has the '*' scope (unrestricted access).
-	starScopeCheck, err := ce.Enforcer.Enforce(userRole.RoleId, permissionConfig.Resources, permissionConfig.Actions, "*")
+	// Check if the role has the '*' scope (unrestricted access).
+	starScopeCheck, err := e.Enforce(roleId, permissionConfig.Resources, permissionConfig.Actions, "*")
 	if err != nil {
 		fmt.Println("Error enforcing policy:", err)
 		return false, false, err
 	}
 
 	// Check permission using the specified scope.
-	reqPermissionCheck, err := ce.Enforcer.Enforce(userRole.RoleId, permissionConfig.Resources, permissionConfig.Actions, permissionConfig.Scopes)
+	reqPermissionCheck, err := e.Enforce(roleId, permissionConfig.Resources, permissionConfig.Actions, permissionConfig.Scopes)
 	if err != nil {
 		fmt.Println("Error enforcing policy:", err)
 		return false, false, err
@@ -46,7 +38,7 @@ func (ce *CasbinEnforcer) VerifyJWTPermission(pbClient *pocketbase.PocketBaseCli
 //
 // Require Resources, Actions and Scopes to be provided in PermissionConfig
 func (ce *CasbinEnforcer) VerifyUserIdPermission(pbClient *pocketbase.PocketBaseClient, userId string, permissionConfig PermissionConfig) (reqPermission bool, starPermission bool, err error) {
-	if ce.Enforcer == nil {
+	if ce.current() == nil {
 		return false, false, fmt.Errorf("casbin Enforcer is not initialized")
 	}
 
@@ -55,23 +47,7 @@ func (ce *CasbinEnforcer) VerifyUserIdPermission(pbClient *pocketbase.PocketBase
 		return false, false, nil
 	}
 
-	// Check if the user has the '*' scope (unrestricted access).
-	starScopeCheck, err := ce.Enforcer.Enforce(userRole.RoleId, permissionConfig.Resources, permissionConfig.Actions, "*")
-	if err != nil {
-		fmt.Println("Error enforcing policy:", err)
-		return false, false, err
-	}
-
-	// Check permission using the specified scope.
-	reqPermissionCheck, err := ce.Enforcer.Enforce(userRole.RoleId, permissionConfig.Resources, permissionConfig.Actions, permissionConfig.Scopes)
-	if err != nil {
-		fmt.Println("Error enforcing policy:", err)
-		return false, false, err
-	}
-
-	hasPermission, starPermission := permissionReturn(reqPermissionCheck, starScopeCheck)
-
-	return hasPermission, starPermission, nil
+	return ce.VerifyRolePermission(userRole.RoleId, permissionConfig)
 }
 
 func permissionReturn(reqScopeBool, starScopeBool bool) (hasPermission bool, starPermission bool) {
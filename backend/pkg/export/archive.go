@@ -0,0 +1,198 @@
+package export
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"time"
+)
+
+// Manifest describes the content of an export archive, it is stored as manifest.json.
+type Manifest struct {
+	UserId    string         `json:"user_id"`
+	Generated time.Time      `json:"generated"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile is an entry of the archive.
+type ManifestFile struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+}
+
+// review is a review the user wrote or received, with its comment.
+type review struct {
+	LabbookId     string `json:"labbook_id"`
+	Title         string `json:"title"`
+	Role          string `json:"role"`
+	ReviewStatus  string `json:"review_status"`
+	ReviewComment string `json:"review_comment,omitempty"`
+}
+
+// share is a lab book shared by or with the user.
+type share struct {
+	LabbookId string   `json:"labbook_id"`
+	Title     string   `json:"title"`
+	Creator   string   `json:"creator,omitempty"`
+	ShareWith []string `json:"share_with,omitempty"`
+}
+
+// archive writes the files of an export and keeps its manifest.
+type archive struct {
+	zw       *zip.Writer
+	manifest Manifest
+}
+
+// build writes the archive of a user's data to dst: profile, settings, the lab books they created or
+// reviewed with their files, review comments and share records.
+func (ex *Exporter) build(userId, dst string) error {
+	user, err := ex.pbClient.ViewUser(userId)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	id := pocketbase.EscapeFilterValue(userId)
+	labbooks, err := ex.pbClient.ListAllLabbooks(url.QueryEscape(fmt.Sprintf("creator='%s' || reviewer='%s'", id, id)), []string{"*"})
+	if err != nil {
+		return err
+	}
+	sharedWithUser, err := ex.pbClient.ListAllLabbooks(url.QueryEscape(fmt.Sprintf("share_with?~'%s'", id)), []string{"id", "title", "creator"})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	a := &archive{
+		zw:       zip.NewWriter(file),
+		manifest: Manifest{UserId: userId, Generated: time.Now().UTC()},
+	}
+
+	// The expanded role and settings are exported on their own
+	user.Expand = nil
+	if err := a.writeJSON("profile.json", "Account profile", user); err != nil {
+		return err
+	}
+
+	if user.Avatar != "" {
+		if err := a.writeFile(ex.pbClient, "profile/"+user.Avatar, "Avatar", "users", user.Id, user.Avatar); err != nil {
+			return err
+		}
+	}
+
+	if user.SettingId != "" {
+		userSettings, err := ex.pbClient.ViewUserSettings(user.SettingId)
+		if err != nil {
+			return err
+		}
+		if err := a.writeJSON("settings.json", "Application settings", userSettings); err != nil {
+			return err
+		}
+	}
+
+	var reviews []review
+	var sharedByUser []share
+	for _, labbook := range labbooks {
+		dir := "lab_books/" + labbook.Id + "/"
+		if err := a.writeJSON(dir+"record.json", "Lab book "+labbook.Title, labbook); err != nil {
+			return err
+		}
+
+		if labbook.File != "" {
+			if err := a.writeFile(ex.pbClient, dir+labbook.File, "Lab book file", "lab_books", labbook.Id, labbook.File); err != nil {
+				return err
+			}
+		}
+		for _, attachment := range labbook.Attachments {
+			if err := a.writeFile(ex.pbClient, dir+"attachments/"+attachment, "Lab book attachment", "lab_books", labbook.Id, attachment); err != nil {
+				return err
+			}
+		}
+
+		role := "creator"
+		if labbook.Reviewer == userId {
+			role = "reviewer"
+		}
+		reviews = append(reviews, review{
+			LabbookId:     labbook.Id,
+			Title:         labbook.Title,
+			Role:          role,
+			ReviewStatus:  labbook.ReviewStatus,
+			ReviewComment: labbook.ReviewComment,
+		})
+
+		if labbook.Creator == userId && len(labbook.ShareWith) > 0 {
+			sharedByUser = append(sharedByUser, share{LabbookId: labbook.Id, Title: labbook.Title, ShareWith: labbook.ShareWith})
+		}
+	}
+
+	if err := a.writeJSON("reviews.json", "Reviews of the lab books created or reviewed, with their comments", reviews); err != nil {
+		return err
+	}
+
+	shares := map[string][]share{"shared_by_user": sharedByUser}
+	for _, labbook := range sharedWithUser {
+		shares["shared_with_user"] = append(shares["shared_with_user"], share{LabbookId: labbook.Id, Title: labbook.Title, Creator: labbook.Creator})
+	}
+	if err := a.writeJSON("shares.json", "Lab books shared by and with the user", shares); err != nil {
+		return err
+	}
+
+	if err := a.writeJSON("manifest.json", "This manifest", a.manifest); err != nil {
+		return err
+	}
+
+	if err := a.zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return file.Close()
+}
+
+// writeJSON adds a JSON document to the archive.
+func (a *archive) writeJSON(name, description string, v interface{}) error {
+	w, err := a.create(name, description)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// writeFile adds a file stored in PocketBase to the archive.
+func (a *archive) writeFile(pbClient *pocketbase.PocketBaseClient, name, description, collection, recordId, filename string) error {
+	w, err := a.create(name, description)
+	if err != nil {
+		return err
+	}
+
+	return pbClient.DownloadFile(collection, recordId, filename, w)
+}
+
+func (a *archive) create(name, description string) (io.Writer, error) {
+	name = path.Clean(name)
+
+	a.manifest.Files = append(a.manifest.Files, ManifestFile{Path: name, Description: description})
+
+	w, err := a.zw.Create(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	return w, nil
+}
@@ -0,0 +1,141 @@
+// Package export builds ZIP archives of all the data held about a user, in the background.
+package export
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// JobLifetime is how long a finished export can be downloaded before its archive is deleted.
+const JobLifetime = 24 * time.Hour
+
+// Export job statuses
+const (
+	JobPending = "pending"
+	JobReady   = "ready"
+	JobFailed  = "failed"
+)
+
+// Job is a requested export of a user's data.
+type Job struct {
+	Id          string     `json:"id"`
+	UserId      string     `json:"user_id"`
+	RequestedBy string     `json:"requested_by"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Created     time.Time  `json:"created"`
+	Finished    *time.Time `json:"finished,omitempty"`
+	path        string
+}
+
+// Exporter runs export jobs and keeps their archives in a directory until they expire.
+type Exporter struct {
+	pbClient *pocketbase.PocketBaseClient
+	dir      string
+	mu       sync.Mutex
+	jobs     *cache.Cache
+}
+
+// NewExporter creates an exporter writing its archives to dir.
+func NewExporter(pbClient *pocketbase.PocketBaseClient, dir string) *Exporter {
+	jobs := cache.New(JobLifetime, time.Hour)
+	jobs.OnEvicted(func(_ string, value interface{}) {
+		if job := value.(Job); job.path != "" {
+			os.Remove(job.path)
+		}
+	})
+
+	return &Exporter{pbClient: pbClient, dir: dir, jobs: jobs}
+}
+
+// Start queues an export of the user's data and returns its job.
+// While an export of the user is still pending, that job is returned instead of starting another one.
+func (ex *Exporter) Start(userId, requestedBy string) (Job, error) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	for _, item := range ex.jobs.Items() {
+		if job := item.Object.(Job); job.UserId == userId && job.Status == JobPending {
+			return job, nil
+		}
+	}
+
+	if err := os.MkdirAll(ex.dir, 0755); err != nil {
+		return Job{}, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	id, err := newJobId()
+	if err != nil {
+		return Job{}, err
+	}
+
+	job := Job{
+		Id:          id,
+		UserId:      userId,
+		RequestedBy: requestedBy,
+		Status:      JobPending,
+		Created:     time.Now().UTC(),
+		path:        filepath.Join(ex.dir, id+".zip"),
+	}
+	ex.jobs.SetDefault(job.Id, job)
+
+	go ex.run(job)
+
+	return job, nil
+}
+
+// Job returns an export job by ID.
+func (ex *Exporter) Job(id string) (Job, bool) {
+	value, found := ex.jobs.Get(id)
+	if !found {
+		return Job{}, false
+	}
+	return value.(Job), true
+}
+
+// Open opens the archive of a finished export job.
+func (ex *Exporter) Open(job Job) (*os.File, error) {
+	if job.Status != JobReady {
+		return nil, fmt.Errorf("export %s is not ready", job.Id)
+	}
+	return os.Open(job.path)
+}
+
+// run builds the archive of a job and records the outcome.
+func (ex *Exporter) run(job Job) {
+	err := ex.build(job.UserId, job.path)
+
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	finished := time.Now().UTC()
+	job.Finished = &finished
+	if err != nil {
+		log.Printf("Failed to export the data of user %s: %v", job.UserId, err)
+		os.Remove(job.path)
+		job.Status = JobFailed
+		job.Error = "Failed to export data"
+	} else {
+		job.Status = JobReady
+	}
+
+	// The download stays available for JobLifetime after the export finished
+	ex.jobs.SetDefault(job.Id, job)
+}
+
+func newJobId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate export id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
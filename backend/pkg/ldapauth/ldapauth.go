@@ -0,0 +1,161 @@
+package ldapauth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found in directory")
+	ErrInvalidCredentials = errors.New("invalid directory credentials")
+)
+
+// Config describes how to reach the directory and how to find users in it.
+type Config struct {
+	Url                string // ldap://host:389 or ldaps://host:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string // Service account used to search users, empty for anonymous search
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // e.g. "(&(objectClass=person)(|(uid=%s)(mail=%s)))", every %s is replaced by the escaped login
+	EmailAttribute     string
+	NameAttribute      string
+	GroupAttribute     string
+	Timeout            time.Duration
+}
+
+// Entry is a user found in the directory after a successful bind.
+type Entry struct {
+	DN     string
+	Email  string
+	Name   string
+	Groups []string
+}
+
+// Authenticator authenticates users by binding against an LDAP / Active Directory server.
+type Authenticator struct {
+	cfg Config
+}
+
+// NewAuthenticator creates an authenticator, filling in the usual attribute names when they are not configured.
+func NewAuthenticator(cfg Config) *Authenticator {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(&(objectClass=person)(|(uid=%s)(mail=%s)))"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "cn"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &Authenticator{cfg: cfg}
+}
+
+// Authenticate looks the user up with the service account, then binds as that user to check the password.
+//
+// It returns ErrUserNotFound when the login does not match exactly one entry
+// and ErrInvalidCredentials when the user exists but the password is wrong.
+func (a *Authenticator) Authenticate(login, password string) (*Entry, error) {
+	// An empty password would be an unauthenticated bind, which many servers accept
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind service account: %w", err)
+		}
+	}
+
+	escapedLogin := ldap.EscapeFilter(login)
+	filter := strings.ReplaceAll(a.cfg.UserFilter, "%s", escapedLogin)
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // Only need to know whether the login is ambiguous
+		int(a.cfg.Timeout.Seconds()),
+		false,
+		filter,
+		[]string{"dn", a.cfg.EmailAttribute, a.cfg.NameAttribute, a.cfg.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search directory: %w", err)
+	}
+
+	if len(result.Entries) != 1 {
+		return nil, ErrUserNotFound
+	}
+	userEntry := result.Entries[0]
+
+	if err := conn.Bind(userEntry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind user: %w", err)
+	}
+
+	return &Entry{
+		DN:     userEntry.DN,
+		Email:  strings.ToLower(userEntry.GetAttributeValue(a.cfg.EmailAttribute)),
+		Name:   userEntry.GetAttributeValue(a.cfg.NameAttribute),
+		Groups: userEntry.GetAttributeValues(a.cfg.GroupAttribute),
+	}, nil
+}
+
+// RoleForGroups returns the role id mapped to the first matching group, or an empty string if none matches.
+//
+// Group DNs are compared case-insensitively. The order of the entry's groups decides ties.
+func RoleForGroups(groups []string, groupRoles map[string]string) string {
+	normalized := make(map[string]string, len(groupRoles))
+	for group, roleId := range groupRoles {
+		normalized[strings.ToLower(group)] = roleId
+	}
+
+	for _, group := range groups {
+		if roleId, ok := normalized[strings.ToLower(group)]; ok {
+			return roleId
+		}
+	}
+	return ""
+}
+
+func (a *Authenticator) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(a.cfg.Url, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to directory: %w", err)
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	return conn, nil
+}
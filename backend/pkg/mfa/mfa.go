@@ -0,0 +1,93 @@
+package mfa
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// Issuer is the name shown for the account in authenticator apps.
+const Issuer = "AlphaLabz"
+
+// recoveryCodeCount is the number of recovery codes generated on enrollment.
+const recoveryCodeCount = 10
+
+// usedCodes remembers the TOTP time steps already used per user, so a code cannot be replayed.
+var usedCodes = cache.New(2*(totpSkew+1)*totpPeriod*time.Second, time.Minute)
+
+// GenerateRecoveryCodes returns new single-use recovery codes and their hashes.
+//
+// Only the hashes are stored, the plain codes are shown to the user once.
+func GenerateRecoveryCodes() (codes []string, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		code := hex.EncodeToString(raw)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, tools.HashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// ValidateUnusedCode checks a TOTP code for a user and refuses codes that were already used.
+func ValidateUnusedCode(userId, secret, code string) bool {
+	step, ok := ValidateCode(secret, code, time.Now())
+	if !ok {
+		return false
+	}
+
+	return usedCodes.Add(fmt.Sprintf("%s:%d", userId, step), true, cache.DefaultExpiration) == nil
+}
+
+// Verify checks a second factor for an enrolled user.
+//
+// The code can either be a TOTP code or one of the recovery codes, a recovery code is consumed when used.
+func Verify(pbClient *pocketbase.PocketBaseClient, record *pocketbase.UserMFA, code string) (bool, error) {
+	if !record.Enabled {
+		return false, nil
+	}
+
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) == totpDigits {
+		return ValidateUnusedCode(record.UserId, record.Secret, code), nil
+	}
+
+	hash := tools.HashToken(code)
+	for i, recoveryHash := range record.RecoveryCodes {
+		if recoveryHash != hash {
+			continue
+		}
+
+		record.RecoveryCodes = append(record.RecoveryCodes[:i], record.RecoveryCodes[i+1:]...)
+		if err := pbClient.SaveUserMFA(record); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// Required reports whether the user's role enforces a second factor.
+func Required(pbClient *pocketbase.PocketBaseClient, roleId string) (bool, error) {
+	if roleId == "" {
+		return false, nil
+	}
+
+	role, err := pbClient.ViewRole(roleId)
+	if err != nil {
+		return false, err
+	}
+
+	return role.RequiresMFA(), nil
+}
@@ -0,0 +1,108 @@
+package mfa
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateUnusedCodeRefusesReplay(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	key, _ := base32NoPadding.DecodeString(secret)
+	code := totpCode(key, time.Now().Unix()/totpPeriod)
+
+	if !ValidateUnusedCode("user0000000001", secret, code) {
+		t.Fatal("ValidateUnusedCode() rejected a fresh code")
+	}
+	if ValidateUnusedCode("user0000000001", secret, code) {
+		t.Error("ValidateUnusedCode() accepted the same code twice")
+	}
+	// Used codes are remembered per user
+	if !ValidateUnusedCode("user0000000002", secret, code) {
+		t.Error("ValidateUnusedCode() rejected a code used by another user")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("GenerateRecoveryCodes() = %d codes, %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	format := regexp.MustCompile(`^[0-9a-f]{5}-[0-9a-f]{5}$`)
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("recovery code %q does not look like xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q generated twice", code)
+		}
+		seen[code] = true
+
+		// Only the hash of the code, without its dash, is stored
+		if want := tools.HashToken(code[:5] + code[6:]); hashes[i] != want {
+			t.Errorf("hash of %q = %s, want %s", code, hashes[i], want)
+		}
+	}
+}
+
+func TestVerifyRecoveryCodeIsSingleUse(t *testing.T) {
+	var saved []pocketbase.UserMFA
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/api/collections/user_mfa/records/mfa000000000001" {
+			t.Errorf("unexpected PocketBase request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+
+		var record pocketbase.UserMFA
+		json.NewDecoder(r.Body).Decode(&record)
+		saved = append(saved, record)
+		record.Id = "mfa000000000001"
+		json.NewEncoder(w).Encode(record)
+	}))
+	defer server.Close()
+	pbClient := &pocketbase.PocketBaseClient{BaseURL: server.URL, HTTPClient: server.Client()}
+
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	secret, _ := GenerateSecret()
+	record := &pocketbase.UserMFA{Id: "mfa000000000001", UserId: "user0000000001", Secret: secret, Enabled: true, RecoveryCodes: append([]string(nil), hashes...)}
+
+	// Codes are accepted with or without dash, in any case
+	for _, code := range []string{codes[3], "  " + strings.ToUpper(codes[5][:5]+codes[5][6:]) + " "} {
+		if ok, err := Verify(pbClient, record, code); err != nil || !ok {
+			t.Fatalf("Verify(%q) = %v, %v, want true", code, ok, err)
+		}
+		if ok, err := Verify(pbClient, record, code); err != nil || ok {
+			t.Errorf("Verify(%q) a second time = %v, %v, want false", code, ok, err)
+		}
+	}
+
+	if len(saved) != 2 {
+		t.Fatalf("the MFA record was saved %d times, want 2", len(saved))
+	}
+	if got := saved[1].RecoveryCodes; len(got) != recoveryCodeCount-2 || tools.Contains(got, hashes[3]) || tools.Contains(got, hashes[5]) {
+		t.Errorf("saved recovery codes = %v, want the %d unused hashes", got, recoveryCodeCount-2)
+	}
+
+	record.Enabled = false
+	if ok, _ := Verify(pbClient, record, codes[0]); ok {
+		t.Error("Verify() accepted a recovery code for a disabled enrollment")
+	}
+}
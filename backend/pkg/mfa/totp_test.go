@@ -0,0 +1,99 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors, "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1, truncated to the 6 digits authenticator apps show
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	key, err := base32NoPadding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.code {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.code)
+		}
+
+		step, ok := ValidateCode(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("ValidateCode(%s at %d) = %d, %v, want %d, true", tt.code, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateCodeWindow(t *testing.T) {
+	// The code of 1234567890 (step 41152263)
+	const code = "005924"
+	issued := time.Unix(1234567890, 0)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{"same step", 0, true},
+		{"one step later", totpPeriod * time.Second, true},
+		{"one step earlier", -totpPeriod * time.Second, true},
+		{"two steps later", 2 * totpPeriod * time.Second, false},
+		{"two steps earlier", -2 * totpPeriod * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateCode(rfcSecret, code, issued.Add(tt.offset))
+			if ok != tt.want {
+				t.Fatalf("ValidateCode() = %v, want %v", ok, tt.want)
+			}
+			// The matched step is the one the code was issued for, not the current one
+			if ok && step != 41152263 {
+				t.Errorf("ValidateCode() step = %d, want 41152263", step)
+			}
+		})
+	}
+}
+
+func TestValidateCodeRejectsMalformedInput(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfcSecret, "005925"},
+		{"too short", rfcSecret, "05924"},
+		{"too long", rfcSecret, "0005924"},
+		{"empty code", rfcSecret, ""},
+		{"invalid secret", "not base32!", "005924"},
+	}
+
+	for _, tt := range tests {
+		if _, ok := ValidateCode(tt.secret, tt.code, at); ok {
+			t.Errorf("%s: ValidateCode() accepted the code", tt.name)
+		}
+	}
+
+	// Authenticator apps may show the secret in lowercase
+	if _, ok := ValidateCode(strings.ToLower(rfcSecret), "005924", at); !ok {
+		t.Error("ValidateCode() rejected a lowercase secret")
+	}
+}
@@ -0,0 +1,221 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/patrickmn/go-cache"
+)
+
+// loginRequestDuration is how long a user has to complete the login at the identity provider.
+const loginRequestDuration = 10 * time.Minute
+
+var ErrUnknownState = errors.New("unknown or expired login state")
+
+// Provider implements the OpenID Connect authorization-code flow with PKCE against a single identity provider.
+type Provider struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+	HTTPClient   *http.Client
+
+	discovery     discoveryDocument
+	keys          *keySet
+	loginRequests *cache.Cache
+}
+
+// discoveryDocument holds the endpoints advertised at /.well-known/openid-configuration
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// loginRequest is the per-login state kept between the redirect to the provider and the callback.
+type loginRequest struct {
+	CodeVerifier string
+	Nonce        string
+}
+
+// NewProvider fetches the provider's discovery document and prepares the flow.
+func NewProvider(issuer, clientId, clientSecret, redirectUrl string, scopes []string, httpClient *http.Client) (*Provider, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	provider := &Provider{
+		Issuer:        strings.TrimSuffix(issuer, "/"),
+		ClientId:      clientId,
+		ClientSecret:  clientSecret,
+		RedirectUrl:   redirectUrl,
+		Scopes:        scopes,
+		HTTPClient:    httpClient,
+		loginRequests: cache.New(loginRequestDuration, 2*loginRequestDuration),
+	}
+
+	if err := provider.fetchDiscovery(); err != nil {
+		return nil, err
+	}
+	provider.keys = newKeySet(provider.discovery.JwksUri, httpClient)
+
+	return provider, nil
+}
+
+// AuthCodeURL creates a new login request and returns the provider URL the user must be redirected to.
+func (p *Provider) AuthCodeURL() (string, error) {
+	state, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := randomString(64)
+	if err != nil {
+		return "", err
+	}
+
+	p.loginRequests.Set(state, loginRequest{CodeVerifier: codeVerifier, Nonce: nonce}, cache.DefaultExpiration)
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientId)
+	query.Set("redirect_uri", p.RedirectUrl)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	return p.discovery.AuthorizationEndpoint + "?" + query.Encode(), nil
+}
+
+// Exchange completes a login request: it redeems the authorization code and returns the verified ID token claims.
+//
+// Each state can only be used once.
+func (p *Provider) Exchange(state, code string) (jwt.MapClaims, error) {
+	pending, found := p.loginRequests.Get(state)
+	if !found {
+		return nil, ErrUnknownState
+	}
+	p.loginRequests.Delete(state)
+	request := pending.(loginRequest)
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectUrl)
+	form.Set("client_id", p.ClientId)
+	form.Set("code_verifier", request.CodeVerifier)
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokenResp struct {
+		IdToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokenResp.IdToken == "" {
+		return nil, errors.New("token response did not contain an id_token")
+	}
+
+	return p.verifyIdToken(tokenResp.IdToken, request.Nonce)
+}
+
+// verifyIdToken checks the ID token signature against the provider keys, then its issuer, audience, expiry and nonce.
+func (p *Provider) verifyIdToken(idToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.key(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) fetchDiscovery() error {
+	resp, err := p.HTTPClient.Get(p.Issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch discovery document: status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&p.discovery); err != nil {
+		return fmt.Errorf("failed to decode discovery document: %w", err)
+	}
+
+	if strings.TrimSuffix(p.discovery.Issuer, "/") != p.Issuer {
+		return fmt.Errorf("discovery issuer %q does not match configured issuer %q", p.discovery.Issuer, p.Issuer)
+	}
+
+	if p.discovery.AuthorizationEndpoint == "" || p.discovery.TokenEndpoint == "" || p.discovery.JwksUri == "" {
+		return errors.New("discovery document is missing required endpoints")
+	}
+
+	return nil
+}
+
+// randomString returns a URL safe random string built from n random bytes.
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
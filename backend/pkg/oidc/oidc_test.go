@@ -0,0 +1,313 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientId    = "alphalabz"
+	testRedirectUrl = "http://localhost:8080/login/oauth/callback"
+	testKeyId       = "key-1"
+)
+
+// authorization is what the mock provider remembers about an authorization code.
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+// mockProvider is an in-process identity provider serving discovery, JWKS and the token endpoint.
+// The token endpoint checks PKCE like a real provider, and ID token claims can be changed per test.
+type mockProvider struct {
+	server *httptest.Server
+
+	mu             sync.Mutex
+	codes          map[string]authorization
+	editClaims     func(claims jwt.MapClaims)
+	signingKey     *rsa.PrivateKey
+	tokenRequests  int
+	lastTokenError string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	mp := &mockProvider{signingKey: key, codes: map[string]authorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 mp.server.URL,
+			"authorization_endpoint": mp.server.URL + "/authorize",
+			"token_endpoint":         mp.server.URL + "/token",
+			"jwks_uri":               mp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": testKeyId,
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", mp.handleToken)
+
+	mp.server = httptest.NewServer(mux)
+	t.Cleanup(mp.server.Close)
+
+	return mp
+}
+
+// authorize plays the user approving the login at the provider: it reads the authorization URL
+// and returns the code and state the browser would bring back to the callback.
+func (mp *mockProvider) authorize(t *testing.T, authUrl string) (code, state string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authUrl)
+	if err != nil {
+		t.Fatalf("invalid authorization URL: %v", err)
+	}
+	query := parsed.Query()
+
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL without S256 code challenge: %s", authUrl)
+	}
+	if query.Get("client_id") != testClientId || query.Get("redirect_uri") != testRedirectUrl {
+		t.Fatalf("authorization URL with wrong client: %s", authUrl)
+	}
+
+	code = "code-" + query.Get("state")[:8]
+	mp.mu.Lock()
+	mp.codes[code] = authorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	mp.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func (mp *mockProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.tokenRequests++
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	auth, ok := mp.codes[r.PostForm.Get("code")]
+	delete(mp.codes, r.PostForm.Get("code"))
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok:
+		mp.lastTokenError = "unknown code"
+	case base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.challenge:
+		mp.lastTokenError = "PKCE verification failed"
+	case r.PostForm.Get("client_id") != testClientId || r.PostForm.Get("redirect_uri") != testRedirectUrl:
+		mp.lastTokenError = "client mismatch"
+	}
+	if mp.lastTokenError != "" {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":            mp.server.URL,
+		"sub":            "idp-subject-1",
+		"aud":            testClientId,
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          auth.nonce,
+		"email":          "ada@univ.edu",
+		"email_verified": true,
+	}
+	if mp.editClaims != nil {
+		mp.editClaims(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyId
+	idToken, err := token.SignedString(mp.signingKey)
+	if err != nil {
+		http.Error(w, "server_error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+}
+
+func newTestProvider(t *testing.T, mp *mockProvider) *Provider {
+	t.Helper()
+
+	provider, err := NewProvider(mp.server.URL, testClientId, "", testRedirectUrl, nil, mp.server.Client())
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	return provider
+}
+
+func TestExchange(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// setup changes the provider, or the code and state brought back to the callback
+		setup     func(mp *mockProvider, provider *Provider, code, state string) (string, string)
+		wantValid bool
+		wantErr   error
+		wantToken bool // whether the token endpoint must have been called
+		wantPKCE  bool // whether the provider must have refused the code verifier
+	}{
+		{
+			name:      "valid login",
+			wantValid: true,
+			wantToken: true,
+		},
+		{
+			name: "unknown state",
+			setup: func(mp *mockProvider, provider *Provider, code, state string) (string, string) {
+				return code, "forged-state"
+			},
+			wantErr: ErrUnknownState,
+		},
+		{
+			name: "state of another login",
+			setup: func(mp *mockProvider, provider *Provider, code, state string) (string, string) {
+				// The code was issued for the first login, the state belongs to a second one: PKCE fails
+				authUrl, _ := provider.AuthCodeURL()
+				parsed, _ := url.Parse(authUrl)
+				return code, parsed.Query().Get("state")
+			},
+			wantToken: true,
+			wantPKCE:  true,
+		},
+		{
+			name: "nonce mismatch",
+			setup: func(mp *mockProvider, provider *Provider, code, state string) (string, string) {
+				mp.editClaims = func(claims jwt.MapClaims) { claims["nonce"] = "replayed-nonce" }
+				return code, state
+			},
+			wantToken: true,
+		},
+		{
+			name: "missing nonce",
+			setup: func(mp *mockProvider, provider *Provider, code, state string) (string, string) {
+				mp.editClaims = func(claims jwt.MapClaims) { delete(claims, "nonce") }
+				return code, state
+			},
+			wantToken: true,
+		},
+		{
+			name: "audience of another client",
+			setup: func(mp *mockProvider, provider *Provider, code, state string) (string, string) {
+				mp.editClaims = func(claims jwt.MapClaims) { claims["aud"] = "another-client" }
+				return code, state
+			},
+			wantToken: true,
+		},
+		{
+			name: "wrong issuer",
+			setup: func(mp *mockProvider, provider *Provider, code, state string) (string, string) {
+				mp.editClaims = func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example" }
+				return code, state
+			},
+			wantToken: true,
+		},
+		{
+			name: "expired id token",
+			setup: func(mp *mockProvider, provider *Provider, code, state string) (string, string) {
+				mp.editClaims = func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }
+				return code, state
+			},
+			wantToken: true,
+		},
+		{
+			name: "id token signed with an unknown key",
+			setup: func(mp *mockProvider, provider *Provider, code, state string) (string, string) {
+				mp.signingKey = otherKey
+				return code, state
+			},
+			wantToken: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := newMockProvider(t)
+			provider := newTestProvider(t, mp)
+
+			authUrl, err := provider.AuthCodeURL()
+			if err != nil {
+				t.Fatalf("AuthCodeURL() error = %v", err)
+			}
+			code, state := mp.authorize(t, authUrl)
+			if tt.setup != nil {
+				code, state = tt.setup(mp, provider, code, state)
+			}
+
+			claims, err := provider.Exchange(state, code)
+
+			switch {
+			case tt.wantValid && err != nil:
+				t.Fatalf("Exchange() error = %v", err)
+			case tt.wantValid && claims["sub"] != "idp-subject-1":
+				t.Fatalf("Exchange() claims = %v", claims)
+			case !tt.wantValid && err == nil:
+				t.Fatalf("Exchange() accepted the login, claims = %v", claims)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Exchange() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if called := mp.tokenRequests > 0; called != tt.wantToken {
+				t.Errorf("token endpoint called = %v, want %v", called, tt.wantToken)
+			}
+			if refused := strings.Contains(mp.lastTokenError, "PKCE"); refused != tt.wantPKCE {
+				t.Errorf("PKCE refused = %v, want %v (%s)", refused, tt.wantPKCE, mp.lastTokenError)
+			}
+		})
+	}
+}
+
+func TestExchangeStateIsSingleUse(t *testing.T) {
+	mp := newMockProvider(t)
+	provider := newTestProvider(t, mp)
+
+	authUrl, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	code, state := mp.authorize(t, authUrl)
+
+	if _, err := provider.Exchange(state, code); err != nil {
+		t.Fatalf("first Exchange() error = %v", err)
+	}
+	if _, err := provider.Exchange(state, code); !errors.Is(err, ErrUnknownState) {
+		t.Fatalf("second Exchange() error = %v, want ErrUnknownState", err)
+	}
+}
@@ -0,0 +1,281 @@
+package passkey
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/patrickmn/go-cache"
+)
+
+// ceremonyTTL is how long a registration or login ceremony may take.
+const ceremonyTTL = 5 * time.Minute
+
+var (
+	ErrUnknownCeremony   = errors.New("unknown or expired ceremony")
+	ErrCredentialUnknown = errors.New("passkey not found")
+	ErrClonedCredential  = errors.New("passkey sign counter went backwards, the authenticator may be cloned")
+)
+
+// Credential is a passkey registered by a user.
+type Credential struct {
+	Name     string              `json:"name"`
+	Created  string              `json:"created"`
+	WebAuthn webauthn.Credential `json:"credential"`
+}
+
+// Id returns the base64url encoded credential ID, used to refer to the passkey in the API.
+func (credential Credential) Id() string {
+	return base64.RawURLEncoding.EncodeToString(credential.WebAuthn.ID)
+}
+
+// ceremony is a registration or login in progress.
+type ceremony struct {
+	session      webauthn.SessionData
+	registration bool
+	userId       string
+}
+
+// Service runs the WebAuthn registration and login ceremonies.
+type Service struct {
+	mu         sync.Mutex
+	webAuthn   *webauthn.WebAuthn
+	store      Store
+	ceremonies *cache.Cache
+}
+
+// NewService creates a passkey service for the relying party (the domain the app is served from).
+func NewService(rpId, rpDisplayName string, rpOrigins []string, store Store) (*Service, error) {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          rpId,
+		RPDisplayName: rpDisplayName,
+		RPOrigins:     rpOrigins,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid webauthn configuration: %w", err)
+	}
+
+	return &Service{
+		webAuthn:   webAuthn,
+		store:      store,
+		ceremonies: cache.New(ceremonyTTL, time.Minute),
+	}, nil
+}
+
+// BeginRegistration starts the registration of a new passkey for a user.
+//
+// The options are passed to navigator.credentials.create() by the browser.
+func (service *Service) BeginRegistration(userId, name, displayName string) (string, *protocol.CredentialCreation, error) {
+	credentials, err := service.store.Credentials(userId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Ask the authenticator not to register the same device twice
+	var exclusions []protocol.CredentialDescriptor
+	for _, credential := range credentials {
+		exclusions = append(exclusions, credential.WebAuthn.Descriptor())
+	}
+
+	account := &user{id: userId, name: name, displayName: displayName, credentials: credentials}
+	options, session, err := service.webAuthn.BeginRegistration(account,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ceremonyId, err := service.startCeremony(ceremony{session: *session, registration: true, userId: userId})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return ceremonyId, options, nil
+}
+
+// FinishRegistration verifies the browser's response to BeginRegistration and stores the new passkey.
+func (service *Service) FinishRegistration(ceremonyId, userId, credentialName string, response io.Reader) (Credential, error) {
+	started, ok := service.takeCeremony(ceremonyId)
+	if !ok || !started.registration || started.userId != userId {
+		return Credential{}, ErrUnknownCeremony
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(response)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	credentials, err := service.store.Credentials(userId)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	account := &user{id: userId, credentials: credentials}
+	created, err := service.webAuthn.CreateCredential(account, started.session, parsed)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if credentialName == "" {
+		credentialName = "Passkey"
+	}
+
+	credential := Credential{
+		Name:     credentialName,
+		Created:  time.Now().UTC().Format(time.RFC3339),
+		WebAuthn: *created,
+	}
+	if err := service.store.SaveCredentials(userId, append(credentials, credential)); err != nil {
+		return Credential{}, err
+	}
+
+	return credential, nil
+}
+
+// BeginLogin starts a passwordless login. Any passkey registered for this site can answer it.
+//
+// The options are passed to navigator.credentials.get() by the browser.
+func (service *Service) BeginLogin() (string, *protocol.CredentialAssertion, error) {
+	options, session, err := service.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ceremonyId, err := service.startCeremony(ceremony{session: *session})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return ceremonyId, options, nil
+}
+
+// FinishLogin verifies the browser's response to BeginLogin and returns the id of the user who logged in.
+func (service *Service) FinishLogin(ceremonyId string, response io.Reader) (string, error) {
+	started, ok := service.takeCeremony(ceremonyId)
+	if !ok || started.registration {
+		return "", ErrUnknownCeremony
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(response)
+	if err != nil {
+		return "", err
+	}
+
+	// The user handle returned by the authenticator is the user id given at registration
+	var account *user
+	loadUser := func(rawId, userHandle []byte) (webauthn.User, error) {
+		credentials, err := service.store.Credentials(string(userHandle))
+		if err != nil {
+			return nil, err
+		}
+		account = &user{id: string(userHandle), credentials: credentials}
+		return account, nil
+	}
+
+	_, validated, err := service.webAuthn.ValidatePasskeyLogin(loadUser, started.session, parsed)
+	if err != nil {
+		return "", err
+	}
+
+	if validated.Authenticator.CloneWarning {
+		return "", ErrClonedCredential
+	}
+
+	// Keep the sign counter up to date so cloned authenticators can be detected
+	for i := range account.credentials {
+		if bytes.Equal(account.credentials[i].WebAuthn.ID, validated.ID) {
+			account.credentials[i].WebAuthn.Authenticator = validated.Authenticator
+			account.credentials[i].WebAuthn.Flags = validated.Flags
+		}
+	}
+	if err := service.store.SaveCredentials(account.id, account.credentials); err != nil {
+		return "", err
+	}
+
+	return account.id, nil
+}
+
+// List returns the passkeys of a user.
+func (service *Service) List(userId string) ([]Credential, error) {
+	return service.store.Credentials(userId)
+}
+
+// Remove deletes a passkey of a user by its base64url credential ID.
+func (service *Service) Remove(userId, credentialId string) error {
+	credentials, err := service.store.Credentials(userId)
+	if err != nil {
+		return err
+	}
+
+	for i, credential := range credentials {
+		if credential.Id() == credentialId {
+			return service.store.SaveCredentials(userId, append(credentials[:i], credentials[i+1:]...))
+		}
+	}
+
+	return ErrCredentialUnknown
+}
+
+func (service *Service) startCeremony(started ceremony) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate ceremony id: %w", err)
+	}
+
+	ceremonyId := hex.EncodeToString(raw)
+	service.ceremonies.Set(ceremonyId, started, cache.DefaultExpiration)
+	return ceremonyId, nil
+}
+
+// takeCeremony returns a ceremony and forgets it, each ceremony can only be finished once.
+func (service *Service) takeCeremony(ceremonyId string) (ceremony, bool) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	cached, found := service.ceremonies.Get(ceremonyId)
+	if !found {
+		return ceremony{}, false
+	}
+	service.ceremonies.Delete(ceremonyId)
+
+	return cached.(ceremony), true
+}
+
+// user adapts a user record to the webauthn.User interface.
+type user struct {
+	id          string
+	name        string
+	displayName string
+	credentials []Credential
+}
+
+func (account *user) WebAuthnID() []byte {
+	return []byte(account.id)
+}
+
+func (account *user) WebAuthnName() string {
+	return account.name
+}
+
+func (account *user) WebAuthnDisplayName() string {
+	return account.displayName
+}
+
+func (account *user) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(account.credentials))
+	for _, credential := range account.credentials {
+		credentials = append(credentials, credential.WebAuthn)
+	}
+	return credentials
+}
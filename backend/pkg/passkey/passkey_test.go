@@ -0,0 +1,233 @@
+package passkey_test
+
+import (
+	"alphalabz/pkg/passkey"
+	"alphalabz/pkg/passkey/passkeytest"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const (
+	testRPId   = "labs.univ.edu"
+	testOrigin = "https://labs.univ.edu"
+	testUserId = "user0000000001"
+)
+
+func newTestService(t *testing.T) (*passkey.Service, *passkeytest.Store) {
+	t.Helper()
+
+	store := passkeytest.NewStore()
+	service, err := passkey.NewService(testRPId, "AlphaLabz", []string{testOrigin}, store)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return service, store
+}
+
+func newTestAuthenticator(t *testing.T) *passkeytest.Authenticator {
+	t.Helper()
+
+	authenticator, err := passkeytest.NewAuthenticator(testRPId, testOrigin)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	return authenticator
+}
+
+// register runs a whole registration ceremony for testUserId.
+func register(t *testing.T, service *passkey.Service, authenticator *passkeytest.Authenticator) passkey.Credential {
+	t.Helper()
+
+	ceremony, options, err := service.BeginRegistration(testUserId, "ada@univ.edu", "Ada Lovelace")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	response, err := authenticator.Register(options.Response)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	credential, err := service.FinishRegistration(ceremony, testUserId, "Lab terminal 3", bytes.NewReader(response))
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	return credential
+}
+
+// login runs a whole login ceremony and returns the user the service recognized.
+func login(t *testing.T, service *passkey.Service, authenticator *passkeytest.Authenticator) (string, error) {
+	t.Helper()
+
+	ceremony, options, err := service.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	response, err := authenticator.Assert(options.Response)
+	if err != nil {
+		t.Fatalf("Assert() error = %v", err)
+	}
+	return service.FinishLogin(ceremony, bytes.NewReader(response))
+}
+
+func storedSignCount(t *testing.T, store *passkeytest.Store) uint32 {
+	t.Helper()
+
+	credentials, _ := store.Credentials(testUserId)
+	if len(credentials) != 1 {
+		t.Fatalf("%d passkeys stored, want 1", len(credentials))
+	}
+	return credentials[0].WebAuthn.Authenticator.SignCount
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	service, store := newTestService(t)
+	authenticator := newTestAuthenticator(t)
+
+	credential := register(t, service, authenticator)
+	if credential.Id() != authenticator.CredentialId() || credential.Name != "Lab terminal 3" {
+		t.Fatalf("registered %q (%s), want %q", credential.Name, credential.Id(), authenticator.CredentialId())
+	}
+
+	for i := 0; i < 2; i++ {
+		userId, err := login(t, service, authenticator)
+		if err != nil {
+			t.Fatalf("FinishLogin() error = %v", err)
+		}
+		if userId != testUserId {
+			t.Fatalf("FinishLogin() = %q, want %q", userId, testUserId)
+		}
+	}
+
+	if count := storedSignCount(t, store); count != 2 {
+		t.Errorf("stored sign count = %d, want 2", count)
+	}
+}
+
+func TestLoginRejectsSignCountRegression(t *testing.T) {
+	service, store := newTestService(t)
+	authenticator := newTestAuthenticator(t)
+	register(t, service, authenticator)
+
+	authenticator.SignCount = 5
+	if _, err := login(t, service, authenticator); err != nil {
+		t.Fatalf("FinishLogin() error = %v", err)
+	}
+
+	// A copy of the key that signed fewer times than the original
+	for _, count := range []uint32{2, 5} {
+		authenticator.SignCount = count
+		if _, err := login(t, service, authenticator); !errors.Is(err, passkey.ErrClonedCredential) {
+			t.Fatalf("FinishLogin() with counter %d error = %v, want ErrClonedCredential", count+1, err)
+		}
+	}
+
+	if count := storedSignCount(t, store); count != 6 {
+		t.Errorf("stored sign count = %d after the rejected logins, want 6", count)
+	}
+
+	// The genuine authenticator keeps working
+	authenticator.SignCount = 6
+	if _, err := login(t, service, authenticator); err != nil {
+		t.Fatalf("FinishLogin() after the rejected logins error = %v", err)
+	}
+}
+
+func TestCeremonies(t *testing.T) {
+	t.Run("registration ceremony of another user", func(t *testing.T) {
+		service, _ := newTestService(t)
+		authenticator := newTestAuthenticator(t)
+
+		ceremony, options, _ := service.BeginRegistration(testUserId, "ada@univ.edu", "Ada Lovelace")
+		response, _ := authenticator.Register(options.Response)
+		if _, err := service.FinishRegistration(ceremony, "user0000000002", "", bytes.NewReader(response)); !errors.Is(err, passkey.ErrUnknownCeremony) {
+			t.Fatalf("FinishRegistration() error = %v, want ErrUnknownCeremony", err)
+		}
+	})
+
+	t.Run("ceremony used twice", func(t *testing.T) {
+		service, _ := newTestService(t)
+		authenticator := newTestAuthenticator(t)
+		register(t, service, authenticator)
+
+		ceremony, options, _ := service.BeginLogin()
+		response, _ := authenticator.Assert(options.Response)
+		if _, err := service.FinishLogin(ceremony, bytes.NewReader(response)); err != nil {
+			t.Fatalf("first FinishLogin() error = %v", err)
+		}
+		if _, err := service.FinishLogin(ceremony, bytes.NewReader(response)); !errors.Is(err, passkey.ErrUnknownCeremony) {
+			t.Fatalf("second FinishLogin() error = %v, want ErrUnknownCeremony", err)
+		}
+	})
+
+	t.Run("registration ceremony used to log in", func(t *testing.T) {
+		service, _ := newTestService(t)
+		authenticator := newTestAuthenticator(t)
+		register(t, service, authenticator)
+
+		ceremony, _, _ := service.BeginRegistration(testUserId, "ada@univ.edu", "Ada Lovelace")
+		_, options, _ := service.BeginLogin()
+		response, _ := authenticator.Assert(options.Response)
+		if _, err := service.FinishLogin(ceremony, bytes.NewReader(response)); !errors.Is(err, passkey.ErrUnknownCeremony) {
+			t.Fatalf("FinishLogin() error = %v, want ErrUnknownCeremony", err)
+		}
+	})
+
+	t.Run("assertion from another origin", func(t *testing.T) {
+		service, _ := newTestService(t)
+		authenticator := newTestAuthenticator(t)
+		register(t, service, authenticator)
+
+		authenticator.Origin = "https://phishing.example"
+		if _, err := login(t, service, authenticator); err == nil {
+			t.Fatal("FinishLogin() accepted an assertion from another origin")
+		}
+	})
+
+	t.Run("unregistered authenticator", func(t *testing.T) {
+		service, _ := newTestService(t)
+		register(t, service, newTestAuthenticator(t))
+
+		// Registered with another service: same user handle, unknown credential
+		other := newTestAuthenticator(t)
+		otherService, _ := newTestService(t)
+		register(t, otherService, other)
+
+		if _, err := login(t, service, other); err == nil {
+			t.Fatal("FinishLogin() accepted an unknown credential")
+		}
+	})
+}
+
+func TestRegistrationExcludesKnownPasskeys(t *testing.T) {
+	service, _ := newTestService(t)
+	authenticator := newTestAuthenticator(t)
+	credential := register(t, service, authenticator)
+
+	_, options, err := service.BeginRegistration(testUserId, "ada@univ.edu", "Ada Lovelace")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	excluded := options.Response.CredentialExcludeList
+	if len(excluded) != 1 || !bytes.Equal(excluded[0].CredentialID, credential.WebAuthn.ID) {
+		t.Fatalf("excluded credentials = %v, want the registered passkey", excluded)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	service, store := newTestService(t)
+	authenticator := newTestAuthenticator(t)
+	register(t, service, authenticator)
+
+	if err := service.Remove(testUserId, "unknown"); !errors.Is(err, passkey.ErrCredentialUnknown) {
+		t.Fatalf("Remove() error = %v, want ErrCredentialUnknown", err)
+	}
+	if err := service.Remove(testUserId, authenticator.CredentialId()); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if credentials, _ := store.Credentials(testUserId); len(credentials) != 0 {
+		t.Fatalf("%d passkeys left, want 0", len(credentials))
+	}
+	if _, err := login(t, service, authenticator); err == nil {
+		t.Fatal("FinishLogin() accepted a removed passkey")
+	}
+}
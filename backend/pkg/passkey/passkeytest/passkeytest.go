@@ -0,0 +1,205 @@
+// Package passkeytest provides a software authenticator and an in-memory store to run the passkey
+// ceremonies in tests, without a browser or a security key.
+package passkeytest
+
+import (
+	"alphalabz/pkg/passkey"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+// Authenticator is a software passkey holding a single P-256 credential.
+//
+// It answers the options of navigator.credentials.create() and navigator.credentials.get()
+// with the JSON a browser would send, always with user presence and user verification.
+type Authenticator struct {
+	// Origin is the origin the browser reports in the client data.
+	Origin string
+	// SignCount is the signature counter, incremented before each assertion.
+	// Tests can lower it to play a cloned authenticator.
+	SignCount uint32
+
+	rpId         string
+	key          *ecdsa.PrivateKey
+	credentialId []byte
+	userHandle   []byte
+}
+
+// NewAuthenticator creates an authenticator with a new key pair for the relying party rpId.
+func NewAuthenticator(rpId, origin string) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialId := make([]byte, 32)
+	if _, err := rand.Read(credentialId); err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{Origin: origin, rpId: rpId, key: key, credentialId: credentialId}, nil
+}
+
+// CredentialId returns the base64url encoded credential ID, as used by the passkey API.
+func (authenticator *Authenticator) CredentialId() string {
+	return base64.RawURLEncoding.EncodeToString(authenticator.credentialId)
+}
+
+// Register answers registration options with a "none" attestation and remembers the user handle.
+func (authenticator *Authenticator) Register(options protocol.PublicKeyCredentialCreationOptions) ([]byte, error) {
+	userHandle, err := decodeUserHandle(options.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	authenticator.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: authenticator.key.X.FillBytes(make([]byte, 32)),
+		YCoord: authenticator.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Attested credential data: AAGUID, credential ID length and ID, then the COSE public key
+	attested := make([]byte, 16, 16+2+len(authenticator.credentialId)+len(publicKey))
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(authenticator.credentialId)))
+	attested = append(attested, authenticator.credentialId...)
+	attested = append(attested, publicKey...)
+
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagAttestedCredentialData
+	authData := append(authenticator.authData(flags), attested...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clientData, err := authenticator.clientData(protocol.CreateCeremony, options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return authenticator.credential(map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+// Assert answers login options by signing the challenge with the next signature counter.
+func (authenticator *Authenticator) Assert(options protocol.PublicKeyCredentialRequestOptions) ([]byte, error) {
+	if authenticator.userHandle == nil {
+		return nil, fmt.Errorf("the authenticator was never registered")
+	}
+
+	authenticator.SignCount++
+	authData := authenticator.authData(protocol.FlagUserPresent | protocol.FlagUserVerified)
+
+	clientData, err := authenticator.clientData(protocol.AssertCeremony, options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, authenticator.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return authenticator.credential(map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(authenticator.userHandle),
+	})
+}
+
+// authData builds the authenticator data: RP ID hash, flags and signature counter.
+func (authenticator *Authenticator) authData(flags protocol.AuthenticatorFlags) []byte {
+	rpIdHash := sha256.Sum256([]byte(authenticator.rpId))
+	authData := append(rpIdHash[:], byte(flags))
+	return binary.BigEndian.AppendUint32(authData, authenticator.SignCount)
+}
+
+func (authenticator *Authenticator) clientData(ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) ([]byte, error) {
+	return json.Marshal(protocol.CollectedClientData{
+		Type:      ceremony,
+		Challenge: encode(challenge),
+		Origin:    authenticator.Origin,
+	})
+}
+
+func (authenticator *Authenticator) credential(response map[string]string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"id":       authenticator.CredentialId(),
+		"rawId":    authenticator.CredentialId(),
+		"type":     "public-key",
+		"response": response,
+	})
+}
+
+// decodeUserHandle reads the user ID of registration options, either as built by go-webauthn
+// or as decoded from the JSON sent to the browser.
+func decodeUserHandle(id interface{}) ([]byte, error) {
+	switch value := id.(type) {
+	case protocol.URLEncodedBase64:
+		return value, nil
+	case []byte:
+		return value, nil
+	case string:
+		return base64.RawURLEncoding.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unsupported user handle %T", id)
+	}
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Store is an in-memory passkey.Store.
+type Store struct {
+	mu          sync.Mutex
+	credentials map[string][]passkey.Credential
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{credentials: map[string][]passkey.Credential{}}
+}
+
+func (store *Store) Credentials(userId string) ([]passkey.Credential, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return append([]passkey.Credential(nil), store.credentials[userId]...), nil
+}
+
+func (store *Store) SaveCredentials(userId string, credentials []passkey.Credential) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.credentials[userId] = append([]passkey.Credential(nil), credentials...)
+	return nil
+}
@@ -0,0 +1,51 @@
+package passkey
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"encoding/json"
+	"fmt"
+)
+
+// Store keeps the passkeys of users.
+//
+// The service only talks to the store, so the ceremonies can run against an in-memory store
+// and a software authenticator in tests.
+type Store interface {
+	Credentials(userId string) ([]Credential, error)
+	SaveCredentials(userId string, credentials []Credential) error
+}
+
+// pocketBaseStore keeps the passkeys in the "webauthn_credentials" field of the users collection.
+type pocketBaseStore struct {
+	pbClient *pocketbase.PocketBaseClient
+}
+
+// NewPocketBaseStore returns a store backed by the users collection.
+func NewPocketBaseStore(pbClient *pocketbase.PocketBaseClient) Store {
+	return &pocketBaseStore{pbClient: pbClient}
+}
+
+func (store *pocketBaseStore) Credentials(userId string) ([]Credential, error) {
+	raw, err := store.pbClient.GetUserPasskeys(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials []Credential
+	if len(raw) == 0 || string(raw) == "null" {
+		return credentials, nil
+	}
+
+	if err := json.Unmarshal(raw, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to decode passkeys: %w", err)
+	}
+
+	return credentials, nil
+}
+
+func (store *pocketBaseStore) SaveCredentials(userId string, credentials []Credential) error {
+	if credentials == nil {
+		credentials = []Credential{}
+	}
+	return store.pbClient.SetUserPasskeys(userId, credentials)
+}
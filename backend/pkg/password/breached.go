@@ -0,0 +1,46 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsBreached looks up a password in a local copy of a breached password list.
+//
+// The list uses the k-anonymity range format of the Pwned Passwords API: the directory holds one file per
+// 5 character SHA-1 prefix (e.g. "5BAA6.txt"), each line being the rest of the hash and a count ("1E4C9B93F3F0682250B6CF8331B7EE68FD8:3").
+// Only the file of the password's prefix is read. A missing file means no breached password has this prefix,
+// and lines with a count of 0 (padding) are ignored.
+func IsBreached(dir, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hashSuffix, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(hashSuffix, suffix) {
+			return strings.TrimSpace(count) != "0", nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return false, nil
+}
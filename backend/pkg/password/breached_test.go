@@ -0,0 +1,92 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newBreachedList writes a breached password list with the given range files (prefix → content).
+func newBreachedList(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for prefix, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIsBreached(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8,
+	// of "P@ssw0rd" 21BD12DC183F740EE76F27B78EB39C8AD972A757, of "letmein" B7A875FC1EA228B9061041B7CEC4BD3C52AB3CE3
+	// and of "password1" E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D
+	dir := newBreachedList(t, map[string]string{
+		"5BAA6": "003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n",
+		"21BD1": "2DC183F740EE76F27B78EB39C8AD972A757:0\n",
+		"B7A87": "5fc1ea228b9061041b7cec4bd3c52ab3ce3:12\n",
+		"E38AD": "0000000000000000000000000000000000A:2\n",
+	})
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"listed", "password", true},
+		{"padding line with a count of 0", "P@ssw0rd", false},
+		{"lowercase hash suffix", "letmein", true},
+		{"prefix file without the suffix", "password1", false},
+		{"no file for the prefix", "correct horse battery staple", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsBreached(dir, tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBreached(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsBreachedUnreadableList checks that a list that cannot be read fails the check instead of
+// letting every password through.
+func TestIsBreachedUnreadableList(t *testing.T) {
+	// A directory where the range file of "password" should be
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "5BAA6.txt"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := IsBreached(dir, "password"); err == nil {
+		t.Error("IsBreached() error = nil, want a read failure")
+	}
+
+	err := Policy{BreachedHashesDir: dir}.Check("password")
+	var policyErr *PolicyError
+	if err == nil || errors.As(err, &policyErr) {
+		t.Errorf("Check() error = %v, want a failure other than PolicyError", err)
+	}
+}
+
+func TestPolicyCheckBreached(t *testing.T) {
+	dir := newBreachedList(t, map[string]string{
+		"5BAA6": "1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\n",
+	})
+	policy := Policy{BreachedHashesDir: dir}
+
+	var policyErr *PolicyError
+	if err := policy.Check("password"); !errors.As(err, &policyErr) {
+		t.Errorf("Check(breached) error = %v, want PolicyError", err)
+	}
+	if err := policy.Check("correct horse battery staple"); err != nil {
+		t.Errorf("Check(not breached) error = %v", err)
+	}
+}
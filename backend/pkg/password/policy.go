@@ -0,0 +1,133 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMinLength is used when the policy does not set a minimum length.
+	DefaultMinLength = 8
+	// MaxLength is the longest password bcrypt (used by PocketBase) can hash.
+	MaxLength = 72
+)
+
+// Policy describes the rules new passwords must follow.
+type Policy struct {
+	MinLength          int
+	RequireUppercase   bool
+	RequireLowercase   bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	ForbidPersonalInfo bool
+	// BreachedHashesDir holds the breached password list, see IsBreached. Empty disables the check.
+	BreachedHashesDir string
+}
+
+// PolicyError is returned when a password does not follow the policy, its message can be shown to the user.
+type PolicyError struct {
+	Reason string
+}
+
+func (err *PolicyError) Error() string {
+	return err.Reason
+}
+
+// Check validates a password against the policy.
+//
+// personalInfo holds the email address and names of the user, a password containing one of them is rejected
+// when ForbidPersonalInfo is set. It returns a *PolicyError for rejected passwords, other errors mean the check failed.
+func (policy Policy) Check(password string, personalInfo ...string) error {
+	minLength := policy.MinLength
+	if minLength <= 0 {
+		minLength = DefaultMinLength
+	}
+
+	if utf8.RuneCountInString(password) < minLength {
+		return &PolicyError{fmt.Sprintf("Password must be at least %d characters long", minLength)}
+	}
+	if len(password) > MaxLength {
+		return &PolicyError{fmt.Sprintf("Password must be at most %d bytes long", MaxLength)}
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
+			hasSymbol = true
+		}
+	}
+
+	var missing []string
+	if policy.RequireUppercase && !hasUpper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if policy.RequireLowercase && !hasLower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		missing = append(missing, "a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		return &PolicyError{"Password must contain " + strings.Join(missing, ", ")}
+	}
+
+	if policy.ForbidPersonalInfo && containsPersonalInfo(password, personalInfo) {
+		return &PolicyError{"Password must not contain your email address or name"}
+	}
+
+	if policy.BreachedHashesDir != "" {
+		breached, err := IsBreached(policy.BreachedHashesDir, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			return &PolicyError{"This password appeared in a data breach, choose another one"}
+		}
+	}
+
+	return nil
+}
+
+// containsPersonalInfo reports whether the password contains the email address, its local part,
+// or one of the words of the names (case-insensitive). Parts shorter than 3 characters are ignored.
+func containsPersonalInfo(password string, personalInfo []string) bool {
+	password = strings.ToLower(password)
+
+	var parts []string
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if info == "" {
+			continue
+		}
+		parts = append(parts, info)
+
+		if at := strings.LastIndex(info, "@"); at > 0 {
+			info = info[:at]
+			parts = append(parts, info)
+		}
+
+		parts = append(parts, strings.FieldsFunc(info, func(char rune) bool {
+			return !unicode.IsLetter(char) && !unicode.IsDigit(char)
+		})...)
+	}
+
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(password, part) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	strict := Policy{
+		MinLength:          10,
+		RequireUppercase:   true,
+		RequireLowercase:   true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		ForbidPersonalInfo: true,
+	}
+	personalInfo := []string{"jane.doe@univ.edu", "Jane Doe"}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		want     string // Expected PolicyError reason, empty if the password is accepted
+	}{
+		{"default minimum length", Policy{}, "short12", "Password must be at least 8 characters long"},
+		{"default policy", Policy{}, "longenough", ""},
+		{"minimum length counts characters", Policy{MinLength: 4}, "éèà", "Password must be at least 4 characters long"},
+		{"multibyte characters", Policy{MinLength: 4}, "éèàü", ""},
+		{"longest bcrypt password", Policy{}, strings.Repeat("a", MaxLength), ""},
+		{"too long for bcrypt", Policy{}, strings.Repeat("a", MaxLength+1), "Password must be at most 72 bytes long"},
+		{"every class", strict, "Lab-Book-2024", ""},
+		{"space counts as symbol", strict, "Lab Book 2024", ""},
+		{"no uppercase", strict, "lab-book-2024", "Password must contain an uppercase letter"},
+		{"no lowercase", strict, "LAB-BOOK-2024", "Password must contain a lowercase letter"},
+		{"no digit", strict, "Lab-Book-Test", "Password must contain a digit"},
+		{"no symbol", strict, "LabBook2024x", "Password must contain a symbol"},
+		{"several classes missing", strict, "labbooktest", "Password must contain an uppercase letter, a digit, a symbol"},
+		{"email address", strict, "Jane.Doe@univ.edu1", "Password must not contain your email address or name"},
+		{"local part of the email", strict, "X1-jane.doe-Y2", "Password must not contain your email address or name"},
+		{"name, any case", strict, "Secret-DOE-2024", "Password must not contain your email address or name"},
+		{"email domain, shared by every user", strict, "Univ-Lab-2024", ""},
+		{"personal info allowed", Policy{}, "jane.doe@univ.edu", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.password, personalInfo...)
+
+			var policyErr *PolicyError
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Check() error = %v, want nil", err)
+			case tt.want != "" && !errors.As(err, &policyErr):
+				t.Errorf("Check() error = %v, want PolicyError %q", err, tt.want)
+			case tt.want != "" && policyErr.Reason != tt.want:
+				t.Errorf("Check() reason = %q, want %q", policyErr.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsPersonalInfoIgnoresShortParts(t *testing.T) {
+	// "Li" and "jo" are too short to be looked for, the password would be rejected for any name otherwise
+	if containsPersonalInfo("gallium-jolly-2024", []string{"jo@univ.edu", "Li Wu"}) {
+		t.Error("containsPersonalInfo() matched a part shorter than 3 characters")
+	}
+	if !containsPersonalInfo("gallium-jolly-2024", []string{"jolly@univ.edu", ""}) {
+		t.Error("containsPersonalInfo() missed the local part of the email address")
+	}
+}
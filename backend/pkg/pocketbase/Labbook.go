@@ -8,6 +8,7 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -135,10 +136,51 @@ func (pbClient *PocketBaseClient) UpdateLabbook(id string, data map[string]inter
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
 
 	return nil
 }
 
+// ReassignLabbooks hands the lab books created or reviewed by a user over to another user.
+// Nobody reviews their own lab book: the ones the other user would both create and review lose their reviewer.
+// It returns the number of lab books updated.
+func (pbClient *PocketBaseClient) ReassignLabbooks(fromUserId, toUserId string) (int, error) {
+	from := EscapeFilterValue(fromUserId)
+	filter := url.QueryEscape(fmt.Sprintf("creator='%s' || reviewer='%s'", from, from))
+
+	// Updated lab books no longer match the filter, so list again until none is left (a page holds 30 records)
+	reassigned := 0
+	for {
+		labbooks, err := pbClient.ListLabbooks(filter, []string{"id", "creator", "reviewer"})
+		if err != nil {
+			return reassigned, err
+		}
+		if len(labbooks) == 0 {
+			return reassigned, nil
+		}
+
+		for _, labbook := range labbooks {
+			data := map[string]interface{}{}
+			if labbook.Creator == fromUserId {
+				data["creator"] = toUserId
+			}
+			if labbook.Reviewer == fromUserId {
+				data["reviewer"] = toUserId
+			}
+			if (labbook.Creator == fromUserId || labbook.Creator == toUserId) && (labbook.Reviewer == fromUserId || labbook.Reviewer == toUserId) {
+				data["reviewer"] = ""
+			}
+
+			if err := pbClient.UpdateLabbook(labbook.Id, data); err != nil {
+				return reassigned, fmt.Errorf("failed to reassign lab book %s: %w", labbook.Id, err)
+			}
+			reassigned++
+		}
+	}
+}
+
 // ListLabbooks retrieves a list of lab book records from PocketBase.
 func (pbClient *PocketBaseClient) ListLabbooks(filter string, fileds []string) ([]Labbook, error) {
 	url := fmt.Sprintf("%s/api/collections/lab_books/records?fields=%s&filter=(%s)", pbClient.BaseURL, strings.Join(fileds, ","), filter)
@@ -170,7 +212,45 @@ func (pbClient *PocketBaseClient) ListLabbooks(filter string, fileds []string) (
 
 }
 
-// ViewLabbook retrieves a lab book record from PocketBase.
+// ListAllLabbooks retrieves every lab book record matching the filter, page by page.
+func (pbClient *PocketBaseClient) ListAllLabbooks(filter string, fileds []string) ([]Labbook, error) {
+	var labbooks []Labbook
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/api/collections/lab_books/records?page=%d&perPage=200&fields=%s&filter=(%s)", pbClient.BaseURL, page, strings.Join(fileds, ","), filter)
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pbClient.SuperToken))
+
+		resp, err := pbClient.HTTPClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+
+		var response struct {
+			Items      []Labbook `json:"items"`
+			TotalPages int       `json:"totalPages"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to get labbooks: %w", errors.New(resp.Status))
+		}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		labbooks = append(labbooks, response.Items...)
+		if page >= response.TotalPages {
+			return labbooks, nil
+		}
+	}
+}
+
+// ViewLabbook retrieves a lab book record from PocketBase, its Id is empty if it does not exist.
 func (pbClient *PocketBaseClient) ViewLabbook(id string, fileds []string) (Labbook, error) {
 	url := fmt.Sprintf("%s/api/collections/lab_books/records/%s?fields=%s", pbClient.BaseURL, id, strings.Join(fileds, ","))
 
@@ -183,10 +263,17 @@ func (pbClient *PocketBaseClient) ViewLabbook(id string, fileds []string) (Labbo
 
 	resp, err := pbClient.HTTPClient.Do(req)
 	if err != nil {
-		return Labbook{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return Labbook{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return Labbook{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Labbook{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
 	var labbook Labbook
 	if err = json.NewDecoder(resp.Body).Decode(&labbook); err != nil {
 		return Labbook{}, fmt.Errorf("failed to decode response body: %w", err)
@@ -195,12 +282,12 @@ func (pbClient *PocketBaseClient) ViewLabbook(id string, fileds []string) (Labbo
 	return labbook, nil
 }
 
-// ShareLabbook updates the share_with field of a lab book record in PocketBase.
+// ShareLabbook adds a recipient to accessList, the current share_with users of a lab book record in PocketBase.
 func (pbClient *PocketBaseClient) ShareLabbook(id string, RecipientId string, accessList []string) error {
 	url := fmt.Sprintf("%s/api/collections/lab_books/records/%s", pbClient.BaseURL, id)
 
 	data := map[string]interface{}{
-		"share_with": RecipientId,
+		"share_with": append(accessList, RecipientId),
 	}
 
 	body, err := json.Marshal(data)
@@ -217,10 +304,14 @@ func (pbClient *PocketBaseClient) ShareLabbook(id string, RecipientId string, ac
 
 	resp, err := pbClient.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,92 @@
+package pocketbase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newLabbooksStub serves the lab_books records of labbooks like PocketBase: the list is filtered on
+// `creator='<id>' || reviewer='<id>'` and PATCH requests update the records in place.
+func newLabbooksStub(t *testing.T, labbooks map[string]*Labbook) *PocketBaseClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := strings.CutPrefix(r.URL.Path, "/api/collections/lab_books/records")
+		switch {
+		case ok && id == "" && r.Method == http.MethodGet:
+			// creator='<id>' || reviewer='<id>', between parentheses
+			userId := strings.Split(r.URL.Query().Get("filter"), "'")[1]
+			items := []Labbook{}
+			for _, labbook := range labbooks {
+				if labbook.Creator == userId || labbook.Reviewer == userId {
+					items = append(items, *labbook)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+
+		case ok && labbooks[strings.TrimPrefix(id, "/")] != nil && r.Method == http.MethodPatch:
+			labbook := labbooks[strings.TrimPrefix(id, "/")]
+			var data map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+			if creator, ok := data["creator"]; ok {
+				labbook.Creator = creator
+			}
+			if reviewer, ok := data["reviewer"]; ok {
+				labbook.Reviewer = reviewer
+			}
+			json.NewEncoder(w).Encode(labbook)
+
+		default:
+			t.Errorf("unexpected PocketBase request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return &PocketBaseClient{BaseURL: server.URL, HTTPClient: server.Client()}
+}
+
+func TestReassignLabbooks(t *testing.T) {
+	const heirId = "heir0000000001"
+
+	labbooks := map[string]*Labbook{
+		"created":      {Id: "created", Creator: testUserId, Reviewer: testOtherUserId},
+		"reviewed":     {Id: "reviewed", Creator: testOtherUserId, Reviewer: testUserId},
+		"heir_reviews": {Id: "heir_reviews", Creator: testUserId, Reviewer: heirId},
+		"heir_created": {Id: "heir_created", Creator: heirId, Reviewer: testUserId},
+		"both":         {Id: "both", Creator: testUserId, Reviewer: testUserId},
+		"unrelated":    {Id: "unrelated", Creator: testOtherUserId, Reviewer: heirId},
+	}
+	pbClient := newLabbooksStub(t, labbooks)
+
+	reassigned, err := pbClient.ReassignLabbooks(testUserId, heirId)
+	if err != nil {
+		t.Fatalf("ReassignLabbooks() error = %v", err)
+	}
+	if reassigned != 5 {
+		t.Errorf("ReassignLabbooks() = %d, want 5", reassigned)
+	}
+
+	// The heir never ends up reviewing a lab book they created
+	want := map[string]Labbook{
+		"created":      {Creator: heirId, Reviewer: testOtherUserId},
+		"reviewed":     {Creator: testOtherUserId, Reviewer: heirId},
+		"heir_reviews": {Creator: heirId},
+		"heir_created": {Creator: heirId},
+		"both":         {Creator: heirId},
+		"unrelated":    {Creator: testOtherUserId, Reviewer: heirId},
+	}
+	for id, want := range want {
+		got := Labbook{Creator: labbooks[id].Creator, Reviewer: labbooks[id].Reviewer}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("lab book %q = %+v, want %+v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package pocketbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// APIToken is a personal access token, stored in the "api_tokens" collection.
+//
+// The collection must only be accessible to superusers (no API rules) and has the fields
+// user (relation to users), name (text), token_hash (text, unique), scopes (json) and expires (date, optional).
+// Only the SHA-256 hash of the token is stored.
+type APIToken struct {
+	Id        string   `json:"id,omitempty"`
+	UserId    string   `json:"user"`
+	Name      string   `json:"name"`
+	TokenHash string   `json:"token_hash,omitempty"`
+	Scopes    []string `json:"scopes"`
+	Expires   string   `json:"expires,omitempty"`
+	Created   string   `json:"created,omitempty"`
+}
+
+// ListAPITokens returns the personal access tokens of a user.
+func (pbClient *PocketBaseClient) ListAPITokens(userId string) ([]APIToken, error) {
+	return pbClient.findAPITokens(fmt.Sprintf("user='%s'", EscapeFilterValue(userId)))
+}
+
+// FindAPITokenByHash returns the personal access token with the given hash.
+//
+// It returns an empty token (without Id) and no error if no token matches.
+func (pbClient *PocketBaseClient) FindAPITokenByHash(tokenHash string) (APIToken, error) {
+	tokens, err := pbClient.findAPITokens(fmt.Sprintf("token_hash='%s'", EscapeFilterValue(tokenHash)))
+	if err != nil || len(tokens) == 0 {
+		return APIToken{}, err
+	}
+
+	return tokens[0], nil
+}
+
+// ViewAPIToken returns a personal access token by its ID.
+//
+// It returns an empty token (without Id) and no error if the token does not exist.
+func (pbClient *PocketBaseClient) ViewAPIToken(tokenId string) (APIToken, error) {
+	tokens, err := pbClient.findAPITokens(fmt.Sprintf("id='%s'", EscapeFilterValue(tokenId)))
+	if err != nil || len(tokens) == 0 {
+		return APIToken{}, err
+	}
+
+	return tokens[0], nil
+}
+
+func (pbClient *PocketBaseClient) findAPITokens(filter string) ([]APIToken, error) {
+	reqUrl := fmt.Sprintf("%s/api/collections/api_tokens/records?perPage=200&sort=-created&filter=%s",
+		pbClient.BaseURL, url.QueryEscape(filter))
+
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch api tokens: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Items []APIToken `json:"items"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return respData.Items, nil
+}
+
+// CreateAPIToken stores a new personal access token and sets its Id.
+func (pbClient *PocketBaseClient) CreateAPIToken(token *APIToken) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/api_tokens/records", pbClient.BaseURL)
+
+	body, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, reqUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to create api token: status %d", resp.StatusCode)
+	}
+
+	var created APIToken
+	if err = json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	token.Id = created.Id
+	token.Created = created.Created
+
+	return nil
+}
+
+// DeleteAPIToken revokes a personal access token.
+func (pbClient *PocketBaseClient) DeleteAPIToken(tokenId string) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/api_tokens/records/%s", pbClient.BaseURL, tokenId)
+
+	req, err := http.NewRequest(http.MethodDelete, reqUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to delete api token: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package pocketbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// AuditLog is an entry of the audit trail, stored in the "audit_logs" collection.
+//
+// The collection must only be accessible to superusers (no API rules) and has the fields
+// actor (relation to users, who acted), user (relation to users, on whose behalf), action (text),
+// method (text), path (text), status (number), ip (text) and details (text).
+type AuditLog struct {
+	Id      string `json:"id,omitempty"`
+	ActorId string `json:"actor"`
+	UserId  string `json:"user,omitempty"`
+	Action  string `json:"action"`
+	Method  string `json:"method,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	IP      string `json:"ip,omitempty"`
+	Details string `json:"details,omitempty"`
+	Created string `json:"created,omitempty"`
+}
+
+// CreateAuditLog appends an entry to the audit trail.
+func (pbClient *PocketBaseClient) CreateAuditLog(entry AuditLog) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/audit_logs/records", pbClient.BaseURL)
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, reqUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to create audit log: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
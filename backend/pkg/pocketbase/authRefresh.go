@@ -0,0 +1,51 @@
+package pocketbase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrTokenNotRefreshable is returned by AuthRefresh when PocketBase accepted the token
+// but refused to renew it (e.g. impersonate tokens).
+var ErrTokenNotRefreshable = errors.New("token is valid but not refreshable")
+
+// AuthRefresh sends a user token to PocketBase's auth-refresh endpoint.
+//
+// PocketBase verifies the token signature before renewing it, so a successful call proves the token is genuine.
+// It returns the renewed token and the user record the token belongs to.
+func (pbClient *PocketBaseClient) AuthRefresh(token string) (string, User, error) {
+	url := fmt.Sprintf("%s/api/collections/users/auth-refresh", pbClient.BaseURL)
+
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return "", User{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return "", User{}, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// PocketBase only reaches the refreshable check after the token signature was verified
+	if resp.StatusCode == http.StatusForbidden {
+		return "", User{}, ErrTokenNotRefreshable
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", User{}, fmt.Errorf("failed to refresh token: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Token  string `json:"token"`
+		Record User   `json:"record"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return "", User{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return respData.Token, respData.Record, nil
+}
@@ -0,0 +1,91 @@
+package pocketbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ExternalAuth links a user to an account at an external identity provider,
+// stored in PocketBase's built-in "_externalAuths" collection.
+type ExternalAuth struct {
+	Id            string `json:"id,omitempty"`
+	CollectionRef string `json:"collectionRef"`
+	RecordRef     string `json:"recordRef"`
+	Provider      string `json:"provider"`
+	ProviderId    string `json:"providerId"`
+}
+
+// FindExternalAuth returns the id of the user linked to an external account.
+//
+// It returns an empty string and no error if the external account is not linked yet.
+func (pbClient *PocketBaseClient) FindExternalAuth(provider, providerId string) (string, error) {
+	filter := url.QueryEscape(fmt.Sprintf("(collectionRef='%s' && provider='%s' && providerId='%s')",
+		pbClient.UsersCollectionId, EscapeFilterValue(provider), EscapeFilterValue(providerId)))
+	reqUrl := fmt.Sprintf("%s/api/collections/_externalAuths/records?filter=%s", pbClient.BaseURL, filter)
+
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch external auths: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Items []ExternalAuth `json:"items"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(respData.Items) == 0 {
+		return "", nil
+	}
+
+	return respData.Items[0].RecordRef, nil
+}
+
+// CreateExternalAuth links a user to an external account.
+func (pbClient *PocketBaseClient) CreateExternalAuth(userId, provider, providerId string) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/_externalAuths/records", pbClient.BaseURL)
+
+	body, err := json.Marshal(ExternalAuth{
+		CollectionRef: pbClient.UsersCollectionId,
+		RecordRef:     userId,
+		Provider:      provider,
+		ProviderId:    providerId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, reqUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to create external auth: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package pocketbase
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// fileToken issues a short-lived token to download protected files as the superuser.
+func (pbClient *PocketBaseClient) fileToken() (string, error) {
+	reqUrl := fmt.Sprintf("%s/api/files/token", pbClient.BaseURL)
+
+	req, err := http.NewRequest(http.MethodPost, reqUrl, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get file token: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return "", fmt.Errorf("failed to decode file token: %w", err)
+	}
+
+	return respData.Token, nil
+}
+
+// DownloadFile copies a file of a record, protected or not, to dst.
+func (pbClient *PocketBaseClient) DownloadFile(collection, recordId, filename string, dst io.Writer) error {
+	token, err := pbClient.fileToken()
+	if err != nil {
+		return err
+	}
+
+	reqUrl := fmt.Sprintf("%s/api/files/%s/%s/%s?token=%s", pbClient.BaseURL,
+		url.PathEscape(collection), url.PathEscape(recordId), url.PathEscape(filename), url.QueryEscape(token))
+
+	resp, err := pbClient.HTTPClient.Get(reqUrl)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: status %d", filename, resp.StatusCode)
+	}
+
+	if _, err := io.Copy(dst, resp.Body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", filename, err)
+	}
+
+	return nil
+}
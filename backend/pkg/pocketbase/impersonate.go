@@ -0,0 +1,46 @@
+package pocketbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ImpersonateUser issues a non-refreshable auth token for a user through the superuser impersonate endpoint.
+//
+// duration is the token lifetime in seconds, 0 uses the users collection default.
+func (pbClient *PocketBaseClient) ImpersonateUser(userId string, duration int) (string, error) {
+	url := fmt.Sprintf("%s/api/collections/users/impersonate/%s", pbClient.BaseURL, userId)
+
+	body, err := json.Marshal(map[string]interface{}{"duration": duration})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal duration: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to impersonate user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to impersonate user: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Token string `json:"token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return "", fmt.Errorf("failed to decode impersonate response: %w", err)
+	}
+
+	return respData.Token, nil
+}
@@ -0,0 +1,132 @@
+package pocketbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+// Invitation is an invite sent to a future user, stored in the "invitations" collection.
+//
+// The collection must only be accessible to superusers (no API rules) and has the fields
+// inviter (relation to users), email (email), name (text), group (text, the course or group of a bulk invite),
+// role (relation to roles), status (select: pending, accepted, revoked), expires (date) and last_sent (date).
+type Invitation struct {
+	Id        string `json:"id,omitempty"`
+	InviterId string `json:"inviter,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Group     string `json:"group,omitempty"`
+	RoleId    string `json:"role,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Expires   string `json:"expires,omitempty"`
+	LastSent  string `json:"last_sent,omitempty"`
+	Created   string `json:"created,omitempty"`
+}
+
+// ListInvitations returns the invitations matching a PocketBase filter, newest first. An empty filter returns all.
+func (pbClient *PocketBaseClient) ListInvitations(filter string) ([]Invitation, error) {
+	reqUrl := fmt.Sprintf("%s/api/collections/invitations/records?perPage=500&sort=-created", pbClient.BaseURL)
+	if filter != "" {
+		reqUrl += "&filter=" + url.QueryEscape(filter)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch invitations: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Items []Invitation `json:"items"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return respData.Items, nil
+}
+
+// ViewInvitation returns an invitation by its ID.
+//
+// It returns an empty invitation (without Id) and no error if it does not exist.
+func (pbClient *PocketBaseClient) ViewInvitation(invitationId string) (Invitation, error) {
+	invitations, err := pbClient.ListInvitations(fmt.Sprintf("id='%s'", EscapeFilterValue(invitationId)))
+	if err != nil || len(invitations) == 0 {
+		return Invitation{}, err
+	}
+
+	return invitations[0], nil
+}
+
+// CreateInvitation stores a new invitation and sets its Id.
+func (pbClient *PocketBaseClient) CreateInvitation(invitation *Invitation) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/invitations/records", pbClient.BaseURL)
+
+	created, err := pbClient.sendInvitation(http.MethodPost, reqUrl, invitation)
+	if err != nil {
+		return err
+	}
+	invitation.Id = created.Id
+	invitation.Created = created.Created
+
+	return nil
+}
+
+// UpdateInvitation updates the non-empty fields of an invitation.
+func (pbClient *PocketBaseClient) UpdateInvitation(invitationId string, invitation Invitation) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/invitations/records/%s", pbClient.BaseURL, invitationId)
+
+	_, err := pbClient.sendInvitation(http.MethodPatch, reqUrl, &invitation)
+	return err
+}
+
+func (pbClient *PocketBaseClient) sendInvitation(method, reqUrl string, invitation *Invitation) (Invitation, error) {
+	body, err := json.Marshal(invitation)
+	if err != nil {
+		return Invitation{}, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(method, reqUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return Invitation{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return Invitation{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Invitation{}, fmt.Errorf("failed to save invitation: status %d", resp.StatusCode)
+	}
+
+	var saved Invitation
+	if err = json.NewDecoder(resp.Body).Decode(&saved); err != nil {
+		return Invitation{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,125 @@
+package pocketbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// UserMFA is the TOTP enrollment of a user, stored in the "user_mfa" collection.
+//
+// The collection must only be accessible to superusers (no API rules) and has the fields
+// user (relation to users, unique), secret (text), enabled (bool) and recovery_codes (json, SHA-256 hashes).
+type UserMFA struct {
+	Id            string   `json:"id,omitempty"`
+	UserId        string   `json:"user"`
+	Secret        string   `json:"secret"`
+	Enabled       bool     `json:"enabled"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// GetUserMFA returns the MFA record of a user.
+//
+// It returns an empty record (without Id) and no error if the user never started an enrollment.
+func (pbClient *PocketBaseClient) GetUserMFA(userId string) (UserMFA, error) {
+	filter := url.QueryEscape(fmt.Sprintf("user='%s'", EscapeFilterValue(userId)))
+	reqUrl := fmt.Sprintf("%s/api/collections/user_mfa/records?filter=%s", pbClient.BaseURL, filter)
+
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return UserMFA{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return UserMFA{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return UserMFA{}, fmt.Errorf("failed to fetch mfa record: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Items []UserMFA `json:"items"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return UserMFA{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(respData.Items) == 0 {
+		return UserMFA{UserId: userId}, nil
+	}
+
+	return respData.Items[0], nil
+}
+
+// SaveUserMFA creates the MFA record of a user, or updates it if it already has an Id.
+func (pbClient *PocketBaseClient) SaveUserMFA(record *UserMFA) error {
+	method := http.MethodPost
+	reqUrl := fmt.Sprintf("%s/api/collections/user_mfa/records", pbClient.BaseURL)
+	if record.Id != "" {
+		method = http.MethodPatch
+		reqUrl += "/" + record.Id
+	}
+
+	if record.RecoveryCodes == nil {
+		record.RecoveryCodes = []string{}
+	}
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(method, reqUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to save mfa record: status %d", resp.StatusCode)
+	}
+
+	var saved UserMFA
+	if err = json.NewDecoder(resp.Body).Decode(&saved); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	record.Id = saved.Id
+
+	return nil
+}
+
+// DeleteUserMFA removes the MFA record of a user, turning the second factor off.
+func (pbClient *PocketBaseClient) DeleteUserMFA(recordId string) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/user_mfa/records/%s", pbClient.BaseURL, recordId)
+
+	req, err := http.NewRequest(http.MethodDelete, reqUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to delete mfa record: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package pocketbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+)
+
+// GetUserPasskeys returns the raw "webauthn_credentials" field of a user.
+//
+// The field is a hidden json field on the users collection, holding the user's registered passkeys.
+// The user id may come from an authenticator's user handle, so it is escaped before being put in the URL.
+func (pbClient *PocketBaseClient) GetUserPasskeys(userId string) (json.RawMessage, error) {
+	url := fmt.Sprintf("%s/api/collections/users/records/%s?fields=webauthn_credentials", pbClient.BaseURL, neturl.PathEscape(userId))
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch passkeys: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Credentials json.RawMessage `json:"webauthn_credentials"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return respData.Credentials, nil
+}
+
+// SetUserPasskeys replaces the "webauthn_credentials" field of a user.
+func (pbClient *PocketBaseClient) SetUserPasskeys(userId string, credentials interface{}) error {
+	url := fmt.Sprintf("%s/api/collections/users/records/%s", pbClient.BaseURL, neturl.PathEscape(userId))
+
+	body, err := json.Marshal(map[string]interface{}{"webauthn_credentials": credentials})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to save passkeys: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
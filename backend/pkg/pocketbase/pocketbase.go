@@ -11,12 +11,17 @@ import (
 	"github.com/patrickmn/go-cache"
 )
 
+// DateLayout is the format of PocketBase date fields.
+const DateLayout = "2006-01-02 15:04:05.000Z"
+
 // PocketBaseClient interacts with the PocketBase HTTP API
 type PocketBaseClient struct {
-	BaseURL       string
-	SuperToken    string
-	HTTPClient    *http.Client
-	UserInfoCache *cache.Cache
+	BaseURL           string
+	SuperToken        string
+	HTTPClient        *http.Client
+	UserInfoCache     *cache.Cache
+	TokenCache        *cache.Cache
+	UsersCollectionId string
 }
 
 // NewPocketBase initializes a new PocketBase client, authenticates, and verifies the connection.
@@ -25,6 +30,7 @@ func NewPocketBase(baseURL, superuserEmail, superuserPassword string, maxRetries
 		BaseURL:       baseURL,
 		HTTPClient:    &http.Client{Timeout: 10 * time.Second},
 		UserInfoCache: cache.New(30*time.Minute, 60*time.Minute),
+		TokenCache:    cache.New(tokenCacheDuration, 2*tokenCacheDuration),
 	}
 
 	// Verify PocketBase connection with retries before proceeding to authentication
@@ -51,6 +57,13 @@ func NewPocketBase(baseURL, superuserEmail, superuserPassword string, maxRetries
 	log.Println("Successfully authenticated superuser")
 	client.SuperToken = token
 
+	// Remember the users collection id so user tokens can be matched against it
+	collectionId, err := client.fetchCollectionId("users")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users collection: %w", err)
+	}
+	client.UsersCollectionId = collectionId
+
 	return client, nil
 }
 
@@ -146,6 +159,36 @@ func (pbClient *PocketBaseClient) authenticateSuperuser(email, password string)
 	return impersonateTokenResp.Token, nil
 }
 
+// fetchCollectionId retrieves the id of a collection by its name.
+func (pbClient *PocketBaseClient) fetchCollectionId(name string) (string, error) {
+	url := fmt.Sprintf("%s/api/collections/%s", pbClient.BaseURL, name)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch collection %s: status %d", name, resp.StatusCode)
+	}
+
+	var collection struct {
+		Id string `json:"id"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&collection); err != nil {
+		return "", fmt.Errorf("failed to decode collection response: %w", err)
+	}
+
+	return collection.Id, nil
+}
+
 // Check pocketbase connection is working
 func (pbClient *PocketBaseClient) CheckConnection() error {
 	url := fmt.Sprintf("%s/api/health", pbClient.BaseURL)
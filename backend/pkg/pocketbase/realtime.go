@@ -0,0 +1,119 @@
+package pocketbase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RealtimeEvent is a record change pushed by PocketBase on a subscribed collection.
+type RealtimeEvent struct {
+	Action string          `json:"action"` // "create", "update" or "delete"
+	Record json.RawMessage `json:"record"`
+}
+
+// SubscribeRealtime listens to the record changes of the given collections, calling handle for each of them.
+// It blocks until the connection is lost or ctx is canceled, and always returns a non-nil error.
+func (pbClient *PocketBaseClient) SubscribeRealtime(ctx context.Context, collections []string, handle func(collection string, event RealtimeEvent)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pbClient.BaseURL+"/api/realtime", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream stays open, so the client timeout of HTTPClient does not apply
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to realtime: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to connect to realtime: status %d", resp.StatusCode)
+	}
+
+	// Changes of any record of a collection are published on the "<collection>/*" topic
+	topics := make([]string, 0, len(collections))
+	subscribed := make(map[string]string, len(collections))
+	for _, collection := range collections {
+		topic := collection + "/*"
+		topics = append(topics, topic)
+		subscribed[topic] = collection
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var name string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		case line == "":
+			// A blank line ends the event
+			if name == "PB_CONNECT" {
+				var connect struct {
+					ClientId string `json:"clientId"`
+				}
+				if err := json.Unmarshal([]byte(data.String()), &connect); err != nil {
+					return fmt.Errorf("failed to decode realtime connect event: %w", err)
+				}
+				if err := pbClient.setRealtimeSubscriptions(ctx, connect.ClientId, topics); err != nil {
+					return err
+				}
+			} else if collection, ok := subscribed[name]; ok {
+				var event RealtimeEvent
+				if err := json.Unmarshal([]byte(data.String()), &event); err == nil {
+					handle(collection, event)
+				}
+			}
+
+			name = ""
+			data.Reset()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("realtime connection lost: %w", err)
+	}
+	return fmt.Errorf("realtime connection closed")
+}
+
+// setRealtimeSubscriptions subscribes a realtime client to topics, as the superuser.
+func (pbClient *PocketBaseClient) setRealtimeSubscriptions(ctx context.Context, clientId string, topics []string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"clientId":      clientId,
+		"subscriptions": topics,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriptions: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pbClient.BaseURL+"/api/realtime", bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to subscribe to realtime: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
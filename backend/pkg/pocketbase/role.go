@@ -8,17 +8,22 @@ import (
 	"strings"
 )
 
+// Role represents a role record from PocketBase
+//
+// The optional parent field (relation to roles) makes the role inherit every permission of its parent.
 type Role struct {
 	Id          string      `json:"id"`
 	Name        string      `json:"name,omitempty"`
 	Description string      `json:"description,omitempty"`
 	Type        string      `json:"type,omitempty"`
+	Parent      string      `json:"parent,omitempty"`
 	Permissions interface{} `json:"permissions,omitempty"`
 }
 
 type NewRoleRequest struct {
 	Name        string      `json:"name"`
 	Description string      `json:"description,omitempty"`
+	Parent      string      `json:"parent,omitempty"`
 	Permissions interface{} `json:"permissions"`
 	Type        string      `json:"type"`
 }
@@ -65,8 +70,8 @@ func (pbClient *PocketBaseClient) ListRoles(fields []string, filter string) (rol
 	return roleListResp.Items, nil
 }
 
-// CreateRole creates a new role in PocketBase.
-func (pbClient *PocketBaseClient) CreateRole(role NewRoleRequest) error {
+// CreateRole creates a new role in PocketBase and returns its ID.
+func (pbClient *PocketBaseClient) CreateRole(role NewRoleRequest) (string, error) {
 	url := fmt.Sprintf("%s/api/collections/roles/records", pbClient.BaseURL)
 
 	data := map[string]interface{}{
@@ -75,34 +80,78 @@ func (pbClient *PocketBaseClient) CreateRole(role NewRoleRequest) error {
 		"permissions": role.Permissions,
 		"type":        "custom",
 	}
+	if role.Parent != "" {
+		data["parent"] = role.Parent
+	}
 
 	body, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
 	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pbClient.SuperToken))
 
 	resp, err := pbClient.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	return nil
+	var createdRole Role
+	if err := json.NewDecoder(resp.Body).Decode(&createdRole); err != nil {
+		return "", fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return createdRole.Id, nil
 }
 
-// func (pbClient *PocketBaseClient) ViewRole(roleId string) (*Role, error) {
+// ViewRole retrieves a single role by its ID.
+func (pbClient *PocketBaseClient) ViewRole(roleId string) (Role, error) {
+	url := fmt.Sprintf("%s/api/collections/roles/records/%s", pbClient.BaseURL, roleId)
 
-// }
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return Role{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return Role{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Role{}, fmt.Errorf("failed to fetch role: received status code %d", resp.StatusCode)
+	}
+
+	var role Role
+	if err = json.NewDecoder(resp.Body).Decode(&role); err != nil {
+		return Role{}, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return role, nil
+}
+
+// RequiresMFA reports whether the role enforces two-factor authentication,
+// set with `"mfa_required": true` in the role's permissions document.
+func (role Role) RequiresMFA() bool {
+	permissions, ok := role.Permissions.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	required, _ := permissions["mfa_required"].(bool)
+	return required
+}
 
 // DeleteRole deletes a role by its ID.
 func (pbClient *PocketBaseClient) DeleteRole(roleId string) error {
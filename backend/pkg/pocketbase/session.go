@@ -0,0 +1,138 @@
+package pocketbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Session is a login of a user, stored in the "sessions" collection.
+//
+// The collection must only be accessible to superusers (no API rules) and has the fields
+// user (relation to users), token_hash (text, unique), ip (text), user_agent (text), expires (date)
+// and impersonator (relation to users, set when an admin impersonates the user).
+// Only the SHA-256 hash of the login token is stored.
+type Session struct {
+	Id        string `json:"id,omitempty"`
+	UserId    string `json:"user"`
+	TokenHash string `json:"token_hash,omitempty"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	Expires   string `json:"expires"`
+	// ImpersonatorId is the admin using this session to impersonate the user, empty for real logins.
+	ImpersonatorId string `json:"impersonator,omitempty"`
+	Created        string `json:"created,omitempty"`
+}
+
+// ListSessions returns the sessions of a user, newest first.
+func (pbClient *PocketBaseClient) ListSessions(userId string) ([]Session, error) {
+	return pbClient.findSessions(fmt.Sprintf("user='%s'", EscapeFilterValue(userId)))
+}
+
+// FindSessionByHash returns the session of a login token.
+//
+// It returns an empty session (without Id) and no error if the token has no session.
+func (pbClient *PocketBaseClient) FindSessionByHash(tokenHash string) (Session, error) {
+	sessions, err := pbClient.findSessions(fmt.Sprintf("token_hash='%s'", EscapeFilterValue(tokenHash)))
+	if err != nil || len(sessions) == 0 {
+		return Session{}, err
+	}
+
+	return sessions[0], nil
+}
+
+// ListExpiredSessions returns the sessions whose token expired before the given PocketBase date.
+func (pbClient *PocketBaseClient) ListExpiredSessions(before string) ([]Session, error) {
+	return pbClient.findSessions(fmt.Sprintf("expires<'%s'", EscapeFilterValue(before)))
+}
+
+func (pbClient *PocketBaseClient) findSessions(filter string) ([]Session, error) {
+	reqUrl := fmt.Sprintf("%s/api/collections/sessions/records?perPage=500&sort=-created&filter=%s",
+		pbClient.BaseURL, url.QueryEscape(filter))
+
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch sessions: status %d", resp.StatusCode)
+	}
+
+	var respData struct {
+		Items []Session `json:"items"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return respData.Items, nil
+}
+
+// CreateSession stores a new session and sets its Id.
+func (pbClient *PocketBaseClient) CreateSession(session *Session) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/sessions/records", pbClient.BaseURL)
+
+	body, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, reqUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to create session: status %d", resp.StatusCode)
+	}
+
+	var created Session
+	if err = json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	session.Id = created.Id
+	session.Created = created.Created
+
+	return nil
+}
+
+// DeleteSession ends a session.
+func (pbClient *PocketBaseClient) DeleteSession(sessionId string) error {
+	reqUrl := fmt.Sprintf("%s/api/collections/sessions/records/%s", pbClient.BaseURL, sessionId)
+
+	req, err := http.NewRequest(http.MethodDelete, reqUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pbClient.SuperToken)
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete session: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,7 +15,17 @@ import (
 	"github.com/patrickmn/go-cache"
 )
 
+// User statuses, users without a status are active
+const (
+	UserStatusActive    = "active"
+	UserStatusPending   = "pending"
+	UserStatusSuspended = "suspended"
+)
+
 // User represents a user record from PocketBase
+//
+// The optional status field (select: active, pending, suspended) holds self-registered users until an admin
+// approves them, and suspended users who keep their history but cannot sign in.
 type User struct {
 	Id        string  `json:"id,omitempty"`
 	Email     string  `json:"email,omitempty"`
@@ -24,11 +35,18 @@ type User struct {
 	RoleId    string  `json:"role,omitempty"`
 	SettingId string  `json:"user_settings,omitempty"`
 	BirthDate string  `json:"birthdate,omitempty"`
+	Status    string  `json:"status,omitempty"`
+	Verified  bool    `json:"verified,omitempty"`
 	Expand    *Expand `json:"expand,omitempty"`
 	Created   string  `json:"created,omitempty"`
 	Updated   string  `json:"updated,omitempty"`
 }
 
+// IsActive reports whether the user may sign in.
+func (user User) IsActive() bool {
+	return user.Status == "" || user.Status == UserStatusActive
+}
+
 type Expand struct {
 	Role        *Role        `json:"role,omitempty"`
 	UserSetting *UserSetting `json:"userSetting,omitempty"`
@@ -134,14 +152,23 @@ func (pbClient *PocketBaseClient) ViewUser(userId string) (User, error) {
 	}
 }
 
-// RegisterUser registers a new user in the "users" collection
-func (pbClient *PocketBaseClient) NewUser(email, password, passwordConfirm, name, gender, birthDate, roleId, avatarPath string) error {
+// RegisterUser registers a new user in the "users" collection and returns the new user id
+func (pbClient *PocketBaseClient) NewUser(email, password, passwordConfirm, name, gender, birthDate, roleId, avatarPath string) (string, error) {
+	return pbClient.createUser(email, password, passwordConfirm, name, gender, birthDate, roleId, avatarPath, UserStatusActive)
+}
+
+// NewPendingUser registers a self-registered user that cannot sign in until an admin approves it.
+func (pbClient *PocketBaseClient) NewPendingUser(email, password, passwordConfirm, name, roleId string) (string, error) {
+	return pbClient.createUser(email, password, passwordConfirm, name, "", "", roleId, "", UserStatusPending)
+}
+
+func (pbClient *PocketBaseClient) createUser(email, password, passwordConfirm, name, gender, birthDate, roleId, avatarPath, status string) (string, error) {
 	url := fmt.Sprintf("%s/api/collections/users/records", pbClient.BaseURL)
 
 	// Create default settings record for new user
 	newSettingId, err := pbClient.createDefaultSettings()
 	if err != nil {
-		return fmt.Errorf("failed to create default settings: %w", err)
+		return "", fmt.Errorf("failed to create default settings: %w", err)
 	}
 
 	// Create new user record
@@ -152,6 +179,7 @@ func (pbClient *PocketBaseClient) NewUser(email, password, passwordConfirm, name
 		"name":            name,
 		"role":            roleId,
 		"user_settings":   newSettingId,
+		"status":          status,
 	}
 
 	// add content to newUserData if the content is not empty
@@ -166,12 +194,12 @@ func (pbClient *PocketBaseClient) NewUser(email, password, passwordConfirm, name
 	// Create request body
 	body, err := json.Marshal(newUserData)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request body, %w", err)
+		return "", fmt.Errorf("failed to marshal request body, %w", err)
 	}
 
 	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pbClient.SuperToken))
@@ -179,11 +207,11 @@ func (pbClient *PocketBaseClient) NewUser(email, password, passwordConfirm, name
 	// Send request
 	resp, err := pbClient.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		return "", fmt.Errorf("failed to create user: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to create user: non-200 status code")
+		return "", fmt.Errorf("failed to create user: non-200 status code")
 	}
 
 	type newUserResp struct {
@@ -194,18 +222,18 @@ func (pbClient *PocketBaseClient) NewUser(email, password, passwordConfirm, name
 
 	var newUserRecord newUserResp
 	if err := json.NewDecoder(resp.Body).Decode(&newUserRecord); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	// Uplaod user avatar
 	if avatarPath != "" {
 		err := pbClient.UpdateAvatar(newUserRecord.Id, avatarPath)
 		if err != nil {
-			return fmt.Errorf("failed to upload avatar file %w", err)
+			return newUserRecord.Id, fmt.Errorf("failed to upload avatar file %w", err)
 		}
 	}
 
-	return nil
+	return newUserRecord.Id, nil
 }
 
 // UpdateAvatar updates the user's profile.
@@ -233,9 +261,81 @@ func (pbClient *PocketBaseClient) UpdateProfile(userId string, newProfile User)
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
+	// Drop the cached copy so the next ViewUser sees the new profile
+	pbClient.UserInfoCache.Delete(userId)
+
+	return nil
+}
+
+// UpdatePassword sets a new password for the user.
+//
+// PocketBase rotates the user's token key on password change, so every token issued before stops working.
+func (pbClient *PocketBaseClient) UpdatePassword(userId, password string) error {
+	url := fmt.Sprintf("%s/api/collections/users/records/%s", pbClient.BaseURL, userId)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"password":        password,
+		"passwordConfirm": password,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pbClient.SuperToken))
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update password: status %d", resp.StatusCode)
+	}
+
+	pbClient.ForgetUserTokens(userId)
+
 	return nil
 }
 
+// UserTokenKey returns the token key of the user, a hidden field only superusers can read.
+//
+// PocketBase rotates it whenever the password or the email address changes. It is never cached.
+func (pbClient *PocketBaseClient) UserTokenKey(userId string) (string, error) {
+	url := fmt.Sprintf("%s/api/collections/users/records/%s?fields=tokenKey", pbClient.BaseURL, userId)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pbClient.SuperToken))
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get token key: status %d", resp.StatusCode)
+	}
+
+	var record struct {
+		TokenKey string `json:"tokenKey"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if record.TokenKey == "" {
+		return "", fmt.Errorf("token key missing from response")
+	}
+
+	return record.TokenKey, nil
+}
+
 // UpdateAvatar updates the user's avatar.
 func (pbClient *PocketBaseClient) UpdateAvatar(userId, avatarPath string) error {
 	url := fmt.Sprintf("%s/api/collections/users/records/%s", pbClient.BaseURL, userId)
@@ -320,6 +420,33 @@ func (pbClient *PocketBaseClient) UpdateSettings(settingsId string, newSettings
 	return nil
 }
 
+// ViewUserSettings retrieves the whole user_settings record of a user.
+func (pbClient *PocketBaseClient) ViewUserSettings(settingsId string) (map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/api/collections/user_settings/records/%s", pbClient.BaseURL, settingsId)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pbClient.SuperToken))
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get settings: status %d", resp.StatusCode)
+	}
+
+	var userSettings map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&userSettings); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return userSettings, nil
+}
+
 // DeleteUser deletes a user by their ID.
 func (pbClient *PocketBaseClient) DeleteUser(userId string) error {
 	url := fmt.Sprintf("%s/api/collections/users/records/%s", pbClient.BaseURL, userId)
@@ -338,6 +465,29 @@ func (pbClient *PocketBaseClient) DeleteUser(userId string) error {
 	if resp.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("failed to delete user: non-204 status code")
 	}
+	pbClient.UserInfoCache.Delete(userId)
+
+	return nil
+}
+
+// DeleteUserSettings deletes the user_settings record of a deleted user.
+func (pbClient *PocketBaseClient) DeleteUserSettings(settingsId string) error {
+	url := fmt.Sprintf("%s/api/collections/user_settings/records/%s", pbClient.BaseURL, settingsId)
+
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pbClient.SuperToken))
+
+	resp, err := pbClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send delete request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete user settings: status %d", resp.StatusCode)
+	}
 
 	return nil
 }
@@ -366,7 +516,31 @@ func (pbClient *PocketBaseClient) CheckUserExists(userId string) (bool, error) {
 	}
 }
 
+// FindUserByEmail looks up a user by email address.
+//
+// It returns an empty User and no error if no user has this email.
+func (pbClient *PocketBaseClient) FindUserByEmail(email string) (User, error) {
+	filter := url.QueryEscape(fmt.Sprintf("(email='%s')", EscapeFilterValue(email)))
+
+	users, _, err := pbClient.ListUsers([]string{"id", "email", "name", "role", "user_settings", "status"}, nil, filter)
+	if err != nil {
+		return User{}, err
+	}
+
+	if len(users) == 0 {
+		return User{}, nil
+	}
+
+	return users[0], nil
+}
+
 // ------------------------------- helper functions -------------------------------
+// EscapeFilterValue escapes a value so it can be safely placed inside a quoted PocketBase filter string.
+func EscapeFilterValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, "'", `\'`)
+}
+
 // createDefaultSettings creates a new default settings record for the user.
 func (pbClient *PocketBaseClient) createDefaultSettings() (newSettingsId string, err error) {
 	url := fmt.Sprintf("%s/api/collections/user_settings/records", pbClient.BaseURL)
@@ -0,0 +1,73 @@
+package pocketbase
+
+import (
+	"alphalabz/pkg/tools"
+	"errors"
+	"time"
+)
+
+// tokenCacheDuration is how long a successful token verification is trusted before asking PocketBase again.
+const tokenCacheDuration = time.Minute
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// VerifyUserToken checks that a token was issued by PocketBase for a record of the users collection.
+//
+// The claims are checked locally first (type, collectionId, exp), then the signature is verified by
+// round-tripping the token through auth-refresh. Successful verifications are cached for a short time
+// keyed by the token hash. It returns the id of the user the token belongs to.
+//
+// auth-refresh is the only PocketBase endpoint that answers differently for a valid and a forged users token:
+// the API rules of the users collection are superuser-only, so any other request made with the user's token
+// is refused either way, and PocketBase never exposes the secret the tokens are signed with.
+// The token it mints is discarded, and thanks to the cache a token is refreshed at most once per tokenCacheDuration.
+func (pbClient *PocketBaseClient) VerifyUserToken(token string) (string, error) {
+	claims, err := tools.ParsePocketBaseJWT(token)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	if claims.Type != "auth" || claims.CollectionId != pbClient.UsersCollectionId || claims.Id == "" {
+		return "", ErrInvalidToken
+	}
+
+	expiresIn := time.Until(time.Unix(int64(claims.Exp), 0))
+	if expiresIn <= 0 {
+		return "", ErrInvalidToken
+	}
+
+	tokenHash := tools.HashToken(token)
+	if userId, found := pbClient.TokenCache.Get(tokenHash); found {
+		return userId.(string), nil
+	}
+
+	_, record, err := pbClient.AuthRefresh(token)
+	switch {
+	case err == nil:
+		if record.Id != claims.Id {
+			return "", ErrInvalidToken
+		}
+	case errors.Is(err, ErrTokenNotRefreshable) && !claims.Refreshable:
+		// Signature is valid, PocketBase only refused to renew a non-refreshable token
+	default:
+		return "", ErrInvalidToken
+	}
+
+	// Never trust the cached result longer than the token itself
+	cacheDuration := tokenCacheDuration
+	if expiresIn < cacheDuration {
+		cacheDuration = expiresIn
+	}
+	pbClient.TokenCache.Set(tokenHash, claims.Id, cacheDuration)
+
+	return claims.Id, nil
+}
+
+// ForgetUserTokens drops every cached verification for a user, e.g. after a password change invalidated their tokens.
+func (pbClient *PocketBaseClient) ForgetUserTokens(userId string) {
+	for tokenHash, item := range pbClient.TokenCache.Items() {
+		if cachedUserId, ok := item.Object.(string); ok && cachedUserId == userId {
+			pbClient.TokenCache.Delete(tokenHash)
+		}
+	}
+}
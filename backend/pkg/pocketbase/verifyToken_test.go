@@ -0,0 +1,196 @@
+package pocketbase
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	testSigningKey   = "pocketbase-signing-key"
+	testUsersId      = "_pb_users_auth_"
+	testOtherCollId  = "pbc_3142635823"
+	testUserId       = "user0000000001"
+	testOtherUserId  = "user0000000002"
+	testForeignKey   = "not-the-pocketbase-key"
+	testRenewedToken = "renewed"
+)
+
+// signTestToken mints a PocketBase-like auth token signed with key.
+func signTestToken(t *testing.T, key, collectionId, userId string, expires time.Time, refreshable bool) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":           userId,
+		"type":         "auth",
+		"collectionId": collectionId,
+		"exp":          expires.Unix(),
+		"refreshable":  refreshable,
+	})
+	signed, err := token.SignedString([]byte(key))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+// newAuthRefreshStub serves auth-refresh like PocketBase: tokens not signed with testSigningKey are
+// rejected with 401, non-refreshable tokens with 403. It counts the requests it received.
+func newAuthRefreshStub(t *testing.T, calls *int) *PocketBaseClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/collections/users/auth-refresh" {
+			http.NotFound(w, r)
+			return
+		}
+		*calls++
+
+		rawToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(rawToken, claims, func(*jwt.Token) (interface{}, error) {
+			return []byte(testSigningKey), nil
+		}, jwt.WithValidMethods([]string{"HS256"}))
+		if err != nil {
+			http.Error(w, `{"message":"The request requires valid record authorization token."}`, http.StatusUnauthorized)
+			return
+		}
+		if refreshable, _ := claims["refreshable"].(bool); !refreshable {
+			http.Error(w, `{"message":"The current auth record is not allowed to refresh."}`, http.StatusForbidden)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":  testRenewedToken,
+			"record": map[string]string{"id": claims["id"].(string)},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return &PocketBaseClient{
+		BaseURL:           server.URL,
+		HTTPClient:        server.Client(),
+		UserInfoCache:     cache.New(time.Minute, time.Minute),
+		TokenCache:        cache.New(tokenCacheDuration, 2*tokenCacheDuration),
+		UsersCollectionId: testUsersId,
+	}
+}
+
+func TestVerifyUserToken(t *testing.T) {
+	hour := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		token     func(t *testing.T) string
+		wantId    string
+		wantCalls int
+	}{
+		{
+			name: "valid token",
+			token: func(t *testing.T) string {
+				return signTestToken(t, testSigningKey, testUsersId, testUserId, hour, true)
+			},
+			wantId:    testUserId,
+			wantCalls: 1,
+		},
+		{
+			name: "valid non-refreshable token",
+			token: func(t *testing.T) string {
+				return signTestToken(t, testSigningKey, testUsersId, testUserId, hour, false)
+			},
+			wantId:    testUserId,
+			wantCalls: 1,
+		},
+		{
+			name: "forged signature",
+			token: func(t *testing.T) string {
+				return signTestToken(t, testForeignKey, testUsersId, testUserId, hour, true)
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "forged non-refreshable token",
+			token: func(t *testing.T) string {
+				return signTestToken(t, testForeignKey, testUsersId, testUserId, hour, false)
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "claims edited after signing",
+			token: func(t *testing.T) string {
+				signed := strings.Split(signTestToken(t, testSigningKey, testUsersId, testUserId, hour, true), ".")
+				edited := strings.Split(signTestToken(t, testForeignKey, testUsersId, testOtherUserId, hour, true), ".")
+				return strings.Join([]string{edited[0], edited[1], signed[2]}, ".")
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T) string {
+				return signTestToken(t, testSigningKey, testUsersId, testUserId, time.Now().Add(-time.Minute), true)
+			},
+		},
+		{
+			name: "token of another collection",
+			token: func(t *testing.T) string {
+				return signTestToken(t, testSigningKey, testOtherCollId, testUserId, hour, true)
+			},
+		},
+		{
+			name: "not a jwt",
+			token: func(t *testing.T) string {
+				return "not-a-token"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			pbClient := newAuthRefreshStub(t, &calls)
+
+			userId, err := pbClient.VerifyUserToken(tt.token(t))
+			if tt.wantId == "" {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("VerifyUserToken() error = %v, want ErrInvalidToken", err)
+				}
+			} else if err != nil || userId != tt.wantId {
+				t.Fatalf("VerifyUserToken() = %q, %v, want %q", userId, err, tt.wantId)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("auth-refresh called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestVerifyUserTokenCache(t *testing.T) {
+	calls := 0
+	pbClient := newAuthRefreshStub(t, &calls)
+	token := signTestToken(t, testSigningKey, testUsersId, testUserId, time.Now().Add(time.Hour), true)
+
+	for i := 0; i < 3; i++ {
+		if _, err := pbClient.VerifyUserToken(token); err != nil {
+			t.Fatalf("VerifyUserToken() error = %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("auth-refresh called %d times, want 1 (cached)", calls)
+	}
+
+	pbClient.ForgetUserTokens(testUserId)
+	if _, err := pbClient.VerifyUserToken(token); err != nil {
+		t.Fatalf("VerifyUserToken() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("auth-refresh called %d times after ForgetUserTokens, want 2", calls)
+	}
+}
@@ -3,37 +3,44 @@ package labbook
 import (
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"net/http"
 )
 
+// Remove Lab Book
+// Deletes a lab book the delete permission scopes on the "lab_books" resource allow:
+// "own" for its creator, "*" for any lab book.
+// The route checks the scopes against the lab book (auth.RequireRecordPermission).
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `DELETE`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the lab book.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "successfully remove lab books from database."
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to delete this lab book.
+//   - 404 Not Found → Lab book does not exist.
+//   - 405 Method Not Allowed → Invalid HTTP method (only DELETE is allowed).
+//   - 500 Internal Server Error → Server issue or failure deleting the lab book.
 func HandleLabBookRemove(w http.ResponseWriter, r *http.Request, labbookId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get request authorization header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Verify user permission using Casbin enforcer
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "delete",
-		Scopes:    "own",
-	})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
 	if err := pbClient.DeleteLabbook(labbookId); err != nil {
 		http.Error(w, "Failed to remove lab book from Pocketbase", http.StatusInternalServerError)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
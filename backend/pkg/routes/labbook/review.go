@@ -1,9 +1,9 @@
 package labbook
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -51,26 +51,15 @@ func HandleLabBookReview(w http.ResponseWriter, r *http.Request, pbClient *pocke
 		return
 	}
 
-	// Get request authorization header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Verify user permission using Casbin enforcer
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "update",
-		Scopes:    "status"})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Decode the JSON request body into a LabBookReviewRequest struct
 	var reviewRequest labbookReviewRequest
-	err = json.NewDecoder(r.Body).Decode(&reviewRequest)
+	err := json.NewDecoder(r.Body).Decode(&reviewRequest)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
@@ -94,15 +83,8 @@ func HandleLabBookReview(w http.ResponseWriter, r *http.Request, pbClient *pocke
 		return
 	}
 
-	// Extract userId from JWT token
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Failed to get user ID", http.StatusInternalServerError)
-		return
-	}
-
 	// Verify that the reviewer is the same as the one in the request body
-	if labbook.Reviewer != userId {
+	if labbook.Reviewer != principal.UserId {
 		http.Error(w, "Unauthorized", http.StatusForbidden)
 		return
 	}
@@ -153,24 +135,6 @@ func GetAvailiableReviewers(w http.ResponseWriter, r *http.Request, pbClient *po
 		return
 	}
 
-	// Get request authorization header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Verify user permission using Casbin enforcer
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "create",
-		Scopes:    "own",
-	})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
 	// Get users with permission to update lab books status
 	hasPermissionList, err := ce.GetRoleIDsByPermission("lab_books", "update", "status")
 	if err != nil {
@@ -238,30 +202,14 @@ func GetPendingReviews(w http.ResponseWriter, r *http.Request, pbClient *pocketb
 		return
 	}
 
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Failed to extract token", http.StatusInternalServerError)
-		return
-	}
-
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "update",
-		Scopes:    "review",
-	})
-	if !hasPermission || err != nil {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
 	// Get the lab book upload history from the database
-	filter := fmt.Sprintf("creator='%s' && review_status='pending'", userId)
+	filter := fmt.Sprintf("creator='%s' && review_status='pending'", principal.UserId)
 	encodedFilter := url.QueryEscape(filter)
 
 	pendingRievews, err := pbClient.ListLabbooks(encodedFilter, []string{"*"})
@@ -1,6 +1,7 @@
 package labbook
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
 	"alphalabz/pkg/tools"
@@ -10,22 +11,25 @@ import (
 )
 
 type ShareRequest struct {
-	LabbookId   string `json:"labbook_id"`
 	RecipientId string `json:"recipient_id"`
 }
 
 // Share Lab Book
-// Only users with the update:"share" permission on the "lab_books" resource can share a lab book with another user.
+// Shares a lab book with another user. Requires the update:"share" permission on the "lab_books" resource,
+// and an update scope that allows this lab book: "own" for its creator, "*" for any lab book.
+// The route checks the scopes against the lab book (auth.RequireRecordPermission).
 //
 // ✅ Authorization:
 // Requires an `Authorization` header with a valid token.
 //
 // ✅ HTTP Method: `POST`
 //
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the lab book to be shared.
+//
 // ✅ Request Body: `Content-Type: application/json`
 // - Fields:
-//   - `LabbookId` (string, required) → The ID of the lab book to be shared.
-//   - `RecipientId` (string, required) → The ID of the user receiving access to the lab book.
+//   - `recipient_id` (string, required) → The ID of the user receiving access to the lab book.
 //
 // ✅ Successful Response (200 OK):
 //
@@ -36,50 +40,32 @@ type ShareRequest struct {
 // ❌ Error Responses:
 //   - 400 Bad Request → Missing required fields or invalid request body format.
 //   - 401 Unauthorized → Missing or Invalid Authorization token.
-//   - 403 Forbidden → User does not have the required permissions.
+//   - 403 Forbidden → User is not allowed to share this lab book.
+//   - 404 Not Found → Lab book or recipient does not exist.
 //   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
-//   - 404 Not Found → Recipient does not exist.
 //   - 409 Conflict → Recipient already has access to the lab book.
 //   - 500 Internal Server Error → Server issue or database operation failure.
-func HandleShareLabbook(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+func HandleShareLabbook(w http.ResponseWriter, r *http.Request, labbookId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
 	// Check if the request method is POST
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get request authorization header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Verify user permission using Casbin enforcer
-	hasPermission, starPermission, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "update",
-		Scopes:    "share",
-	})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
 	// Parse request body into a struct
 	var shareRequest ShareRequest
-	err = json.NewDecoder(r.Body).Decode(&shareRequest)
+	err := json.NewDecoder(r.Body).Decode(&shareRequest)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if shareRequest.LabbookId == "" || shareRequest.RecipientId == "" {
+	if shareRequest.RecipientId == "" {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
 
-	labbookInfo, err := pbClient.ViewLabbook(shareRequest.LabbookId, []string{"id", "creator", "share_to"})
+	labbookInfo, err := pbClient.ViewLabbook(labbookId, []string{"id", "creator", "reviewer", "share_with"})
 	if err != nil {
 		http.Error(w, "Failed to retrieve labbook information", http.StatusInternalServerError)
 		return
@@ -90,17 +76,6 @@ func HandleShareLabbook(w http.ResponseWriter, r *http.Request, pbClient *pocket
 		return
 	}
 
-	requester, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Failed to obtain requester ID from token", http.StatusInternalServerError)
-		return
-	}
-
-	if labbookInfo.Creator != requester && !starPermission {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
 	recipientExist, err := pbClient.CheckUserExists(shareRequest.RecipientId)
 	if err != nil {
 		http.Error(w, "Failed to check if recipient exists", http.StatusInternalServerError)
@@ -111,7 +86,7 @@ func HandleShareLabbook(w http.ResponseWriter, r *http.Request, pbClient *pocket
 		http.Error(w, "Recipient does not exist", http.StatusNotFound)
 		return
 	} else {
-		err := pbClient.ShareLabbook(shareRequest.LabbookId, shareRequest.RecipientId, labbookInfo.ShareWith)
+		err := pbClient.ShareLabbook(labbookId, shareRequest.RecipientId, labbookInfo.ShareWith)
 		if err != nil {
 			http.Error(w, "Failed to share labbook", http.StatusInternalServerError)
 			return
@@ -144,33 +119,15 @@ func GetSharedList(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.
 		return
 	}
 
-	// Get request authorization header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Verify user permission using Casbin enforcer
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "view",
-		Scopes:    "shared",
-	})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get the lab books that have been shared with the user from the database
 
-	sharedLabbooks, err := pbClient.ListLabbooks(fmt.Sprintf("share_with?~'%s'", userId), []string{"*"})
+	sharedLabbooks, err := pbClient.ListLabbooks(fmt.Sprintf("share_with?~'%s'", principal.UserId), []string{"*"})
 	if err != nil {
 		http.Error(w, "Failed to get shared labbooks", http.StatusInternalServerError)
 		return
@@ -1,6 +1,7 @@
 package labbook
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
 	"alphalabz/pkg/settings"
@@ -56,21 +57,9 @@ func HandleLabBookUpload(w http.ResponseWriter, r *http.Request, pbClient *pocke
 	// Constrain form size
 	r.ParseMultipartForm(settings.MaxLabbookSize << 20)
 
-	// Get request authorization header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Verify user permission using Casbin enforcer
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "create",
-		Scopes:    "own",
-	})
-	if err != nil || !hasPermission {
-		http.Error(w, "Failed to verify permission", http.StatusInternalServerError)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -206,15 +195,8 @@ func HandleLabBookUpload(w http.ResponseWriter, r *http.Request, pbClient *pocke
 		}
 	}
 
-	// Get user details from request body
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
 	// Pass attachments to UploadLabbook
-	err = pbClient.UploadLabbook(title, description, userId, reviewerId, filePath, attachmentPaths)
+	err = pbClient.UploadLabbook(title, description, principal.UserId, reviewerId, filePath, attachmentPaths)
 	if err != nil {
 		http.Error(w, "Failed to upload labbook", http.StatusInternalServerError)
 		return
@@ -227,7 +209,7 @@ func HandleLabBookUpload(w http.ResponseWriter, r *http.Request, pbClient *pocke
 		"content": map[string]string{
 			"title":       title,
 			"description": description,
-			"userId":      userId,
+			"userId":      principal.UserId,
 			"reviewerId":  reviewerId,
 		},
 	})
@@ -255,29 +237,13 @@ func HandleLabbookUploadHistory(w http.ResponseWriter, r *http.Request, pbClient
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Check if the user has permission to review lab books
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "view",
-		Scopes:    "own"})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	uploadHistory, err := pbClient.ListLabbooks(fmt.Sprintf("creator='%s'", userId), []string{"*"})
+	uploadHistory, err := pbClient.ListLabbooks(fmt.Sprintf("creator='%s'", principal.UserId), []string{"*"})
 	if err != nil {
 		fmt.Println(err)
 		http.Error(w, "Failed to get lab book upload history", http.StatusInternalServerError)
@@ -3,11 +3,32 @@ package labbook
 import (
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"net/http"
 )
 
+// View Lab Book
+// Returns a lab book to the users its view permission scopes on the "lab_books" resource allow:
+// "own" for its creator, "shared" for the users it is shared with and its reviewer, "*" for anyone.
+// The route checks the scopes against the lab book (auth.RequireRecordPermission).
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the lab book.
+//
+// ✅ Successful Response (200 OK):
+// Returns the lab book record as JSON.
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to view this lab book.
+//   - 404 Not Found → Lab book does not exist.
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+//   - 500 Internal Server Error → Server issue or failure retrieving the lab book.
 func HandleLabBookView(w http.ResponseWriter, r *http.Request, labbookId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
 	// Check if the request method is GET.
 	if r.Method != http.MethodGet {
@@ -15,36 +36,12 @@ func HandleLabBookView(w http.ResponseWriter, r *http.Request, labbookId string,
 		return
 	}
 
-	// Get request authorization header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Failed to obtain userId from token", http.StatusInternalServerError)
-	}
-
-	hasStarPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "lab_books",
-		Actions:   "view",
-		Scopes:    "*",
-	})
-	if err != nil {
-		http.Error(w, "Failed to verify permission", http.StatusInternalServerError)
-	}
-
 	labbookContent, err := pbClient.ViewLabbook(labbookId, []string{"*"})
 	if err != nil {
 		http.Error(w, "Failed to view labbook", http.StatusInternalServerError)
+		return
 	}
 
-	// if userId in access list or hasStarPermission
-	if hasStarPermission || tools.Contains(labbookContent.ShareWith, userId) {
-		json.NewEncoder(w).Encode(labbookContent)
-	} else {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(labbookContent)
 }
@@ -1,9 +1,16 @@
 package login
 
 import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/ldapauth"
 	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/smtp"
 	"alphalabz/pkg/tools"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 )
 
@@ -13,6 +20,13 @@ type loginRequest struct {
 }
 
 // Login with Email & Password
+// When LDAP is enabled the credentials are checked against the directory first,
+// and only users unknown to the directory fall back to the local password (if allowed in settings).
+// Users with MFA enrolled, or whose role enforces MFA, get a challenge instead of the token,
+// which is released by `/login/mfa` once the second factor is verified.
+// Failed attempts are tracked per account and per IP address: after a few failures every attempt has to wait
+// for an exponential backoff, and too many failures lock the account (the owner is notified by email).
+//
 // ✅ Request Body (JSON):
 //
 //	{
@@ -28,22 +42,92 @@ type loginRequest struct {
 //		    "token": "your-auth-token"
 //	}
 //
+// ✅ Second Factor Required (200 OK):
+//
+//	{
+//			"status": "mfa_required",
+//			"timestamp": "2025-01-30 17:23:01",
+//		    "challenge": "challenge-token",
+//		    "enrollment_required": false
+//	}
+//
 // ❌ Error Responses:
 //   - 400 Bad Request → Invalid JSON or missing fields
+//   - 401 Unauthorized → Invalid credentials
+//   - 403 Forbidden → Directory account without a mapped role, or account pending administrator approval or suspended
+//   - 429 Too Many Requests → Backoff or lockout in effect, see the `Retry-After` header
 //   - 500 Internal Server Error → Server issue
-func HandleAccountLogin(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient) {
+func HandleAccountLogin(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, directory *ldapauth.Authenticator, lg *auth.LoginGuard, sc *smtp.SMTPClient, sr *auth.SessionRegistry) {
 	var loginData loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&loginData); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	token, err := pbClient.AuthUserWithPassword(loginData.Email, loginData.Password)
+	clientIP := tools.ClientIP(r)
+	if wait := lg.RetryAfter(loginData.Email, clientIP); wait > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	var token string
+	var err error
+	useLocal := directory == nil
+
+	if directory != nil {
+		token, useLocal, err = loginWithDirectory(pbClient, directory, loginData.Email, loginData.Password)
+		if errors.Is(err, errNoDirectoryRole) {
+			http.Error(w, "No role assigned for this directory account", http.StatusForbidden)
+			return
+		}
+	}
+
+	if useLocal {
+		token, err = pbClient.AuthUserWithPassword(loginData.Email, loginData.Password)
+	}
+
 	if err != nil {
+		if lg.Failure(loginData.Email, clientIP) {
+			go notifyAccountLocked(pbClient, sc, loginData.Email, clientIP)
+		}
 		http.Error(w, "Authentication failed", http.StatusUnauthorized)
 		return
 	}
 
+	userId, err := tools.GetUserIdFromJWT(token)
+	if err != nil {
+		http.Error(w, "Failed to read token", http.StatusInternalServerError)
+		return
+	}
+	if refuseInactiveUser(w, pbClient, userId) {
+		return
+	}
+
+	challenge, enroll, err := startMFAChallenge(pbClient, token, loginData.Email)
+	if err != nil {
+		http.Error(w, "Failed to check MFA settings", http.StatusInternalServerError)
+		return
+	}
+
+	// The failures are only forgotten once the second factor passed too
+	if challenge != "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":              "mfa_required",
+			"challenge":           challenge,
+			"enrollment_required": enroll,
+			"timestamp":           tools.Timestamp(),
+		})
+		return
+	}
+	lg.Success(loginData.Email)
+
+	if _, err := sr.Register(token, r); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":    "success",
@@ -51,3 +135,25 @@ func HandleAccountLogin(w http.ResponseWriter, r *http.Request, pbClient *pocket
 		"timestamp": tools.Timestamp(),
 	})
 }
+
+// notifyAccountLocked tells the owner of an account that it was locked, if the account exists.
+func notifyAccountLocked(pbClient *pocketbase.PocketBaseClient, sc *smtp.SMTPClient, email, clientIP string) {
+	user, err := pbClient.FindUserByEmail(email)
+	if err != nil || user.Id == "" {
+		return
+	}
+
+	body, err := smtp.RenderTemplate("account_locked.html", map[string]string{
+		"Name":      user.Name,
+		"IP":        clientIP,
+		"LockedFor": fmt.Sprintf("%d minutes", int(auth.LoginLockoutPeriod.Minutes())),
+	})
+	if err != nil {
+		log.Println("Failed to render account locked email:", err)
+		return
+	}
+
+	if _, err := sc.SendMail("Your AlphaLabz account has been locked", body, user.Email); err != nil {
+		log.Println("Failed to send account locked email:", err)
+	}
+}
@@ -0,0 +1,129 @@
+package login
+
+import (
+	"alphalabz/pkg/ldapauth"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/settings"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ldapProviderName is the provider name used to link users in PocketBase's _externalAuths collection.
+const ldapProviderName = "ldap"
+
+var errNoDirectoryRole = errors.New("no role mapped for directory user")
+
+// loginWithDirectory authenticates against the directory, syncs the user into PocketBase and issues a token.
+//
+// useLocal is true when the login should be retried with the local PocketBase password instead.
+func loginWithDirectory(pbClient *pocketbase.PocketBaseClient, directory *ldapauth.Authenticator, email, password string) (token string, useLocal bool, err error) {
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load settings: %w", err)
+	}
+	ldapSettings := settings.LDAP
+
+	entry, err := directory.Authenticate(email, password)
+	if errors.Is(err, ldapauth.ErrInvalidCredentials) {
+		// The account lives in the directory, do not let an old local password bypass it
+		return "", false, err
+	} else if err != nil {
+		if !errors.Is(err, ldapauth.ErrUserNotFound) {
+			log.Println("LDAP authentication failed:", err)
+		}
+		return "", ldapSettings.FallbackToLocal, err
+	}
+
+	if entry.Email == "" {
+		return "", false, fmt.Errorf("directory entry %s has no email address", entry.DN)
+	}
+
+	roleId, err := mappedDirectoryRole(pbClient, entry.Groups, ldapSettings.GroupRoles)
+	if err != nil {
+		return "", false, err
+	}
+
+	userId, err := syncDirectoryUser(pbClient, entry, roleId, ldapSettings.DefaultRoleId)
+	if err != nil {
+		return "", false, err
+	}
+
+	token, err = pbClient.ImpersonateUser(userId, 0)
+	if err != nil {
+		return "", false, err
+	}
+
+	return token, false, nil
+}
+
+// mappedDirectoryRole maps directory groups to a role id that exists in the roles collection.
+func mappedDirectoryRole(pbClient *pocketbase.PocketBaseClient, groups []string, groupRoles map[string]string) (string, error) {
+	roleId := ldapauth.RoleForGroups(groups, groupRoles)
+	if roleId == "" {
+		return "", nil
+	}
+
+	roles, err := pbClient.ListRoles([]string{"id"}, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	for _, role := range roles {
+		if role.Id == roleId {
+			return roleId, nil
+		}
+	}
+
+	log.Printf("LDAP group mapping points to unknown role %s, ignoring it", roleId)
+	return "", nil
+}
+
+// syncDirectoryUser finds (or creates) the PocketBase user for a directory entry and copies name, email and role over.
+func syncDirectoryUser(pbClient *pocketbase.PocketBaseClient, entry *ldapauth.Entry, roleId, defaultRoleId string) (string, error) {
+	userId, err := pbClient.FindExternalAuth(ldapProviderName, entry.DN)
+	if err != nil {
+		return "", err
+	}
+	linked := userId != ""
+
+	if !linked {
+		user, err := pbClient.FindUserByEmail(entry.Email)
+		if err != nil {
+			return "", err
+		}
+		userId = user.Id
+	}
+
+	if userId == "" {
+		if roleId == "" {
+			roleId = defaultRoleId
+		}
+		if roleId == "" {
+			return "", errNoDirectoryRole
+		}
+
+		// Directory addresses are managed by the organization, no need to verify them again
+		userId, err = provisionUser(pbClient, entry.Email, entry.Name, roleId, true)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// Role is only updated when a group mapping matched, otherwise the role set in AlphaLabz is kept
+		if err := pbClient.UpdateProfile(userId, pocketbase.User{
+			Email:  entry.Email,
+			Name:   entry.Name,
+			RoleId: roleId,
+		}); err != nil {
+			return "", fmt.Errorf("failed to sync directory user: %w", err)
+		}
+	}
+
+	if !linked {
+		if err := pbClient.CreateExternalAuth(userId, ldapProviderName, entry.DN); err != nil {
+			return "", err
+		}
+	}
+
+	return userId, nil
+}
@@ -0,0 +1,351 @@
+package login
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/ldapauth"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	testServiceDN       = "cn=alphalabz,ou=services,dc=univ,dc=edu"
+	testServicePassword = "service-secret"
+	testStudentsGroup   = "cn=students,ou=groups,dc=univ,dc=edu"
+	testTeachersGroup   = "cn=teachers,ou=groups,dc=univ,dc=edu"
+)
+
+// directoryEntry is a user of the LDAP stand-in.
+type directoryEntry struct {
+	dn       string
+	uid      string
+	password string
+	attrs    map[string][]string
+}
+
+// ldapStandIn is a minimal in-process LDAP server answering the simple binds and searches of ldapauth.
+type ldapStandIn struct {
+	listener net.Listener
+	entries  []directoryEntry
+}
+
+func newLDAPStandIn(t *testing.T, entries ...directoryEntry) *ldapStandIn {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	standIn := &ldapStandIn{listener: listener, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go standIn.serve(conn)
+		}
+	}()
+
+	return standIn
+}
+
+func (s *ldapStandIn) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *ldapStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageId := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := request.Children[1].Value.(string)
+			password := request.Children[2].Data.String()
+			conn.Write(ldapResponse(messageId, ldap.ApplicationBindResponse, s.bindResult(dn, password)).Bytes())
+
+		case ldap.ApplicationSearchRequest:
+			filter, err := ldap.DecompileFilter(request.Children[6])
+			if err != nil {
+				conn.Write(ldapResponse(messageId, ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError).Bytes())
+				continue
+			}
+			for _, entry := range s.entries {
+				if strings.Contains(filter, "(uid="+entry.uid+")") || strings.Contains(filter, "(mail="+entry.attrs["mail"][0]+")") {
+					conn.Write(searchResultEntry(messageId, entry).Bytes())
+				}
+			}
+			conn.Write(ldapResponse(messageId, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess).Bytes())
+
+		default: // Unbind or anything unsupported
+			return
+		}
+	}
+}
+
+func (s *ldapStandIn) bindResult(dn, password string) uint16 {
+	if dn == testServiceDN && password == testServicePassword {
+		return ldap.LDAPResultSuccess
+	}
+	for _, entry := range s.entries {
+		if entry.dn == dn && entry.password == password {
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+func ldapResponse(messageId int64, tag ber.Tag, resultCode uint16) *ber.Packet {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "MessageID"))
+
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "resultCode"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	envelope.AppendChild(response)
+
+	return envelope
+}
+
+func searchResultEntry(messageId int64, entry directoryEntry) *ber.Packet {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "MessageID"))
+
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range entry.attrs {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "val"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	result.AppendChild(attributes)
+	envelope.AppendChild(result)
+
+	return envelope
+}
+
+func newDirectoryEntry(uid, password string, groups ...string) directoryEntry {
+	return directoryEntry{
+		dn:       fmt.Sprintf("uid=%s,ou=people,dc=univ,dc=edu", uid),
+		uid:      uid,
+		password: password,
+		attrs: map[string][]string{
+			"mail":     {uid + "@univ.edu"},
+			"cn":       {strings.ToUpper(uid[:1]) + uid[1:] + " Lovelace"},
+			"memberOf": groups,
+		},
+	}
+}
+
+// useDirectorySettings writes the LDAP section of settings.yml: students map to role 0003, teachers to the
+// unknown role 9999, and users without a mapped group get defaultRoleId.
+func useDirectorySettings(t *testing.T, defaultRoleId string, fallbackToLocal bool) {
+	t.Helper()
+
+	useSettings(t, fmt.Sprintf(`LDAP:
+  enabled: true
+  group_roles:
+    %q: "0003"
+    %q: "9999"
+  default_role_id: %q
+  fallback_to_local: %v
+`, testStudentsGroup, testTeachersGroup, defaultRoleId, fallbackToLocal))
+}
+
+func newTestDirectory(standIn *ldapStandIn) *ldapauth.Authenticator {
+	return ldapauth.NewAuthenticator(ldapauth.Config{
+		Url:          standIn.url(),
+		BindDN:       testServiceDN,
+		BindPassword: testServicePassword,
+		BaseDN:       "dc=univ,dc=edu",
+	})
+}
+
+func TestLoginWithDirectory(t *testing.T) {
+	tests := []struct {
+		name            string
+		login, password string
+		defaultRoleId   string
+		fallbackToLocal bool
+		// localUser is the role of an existing local account with the same email, empty for none
+		localUser    string
+		wantErr      error
+		wantUseLocal bool
+		wantRole     string
+	}{
+		{
+			name:  "new user with a mapped group",
+			login: "ada", password: "ada-password",
+			defaultRoleId: "0004",
+			wantRole:      "0003",
+		},
+		{
+			name:  "new user without a mapped group gets the default role",
+			login: "grace", password: "grace-password",
+			defaultRoleId: "0004",
+			wantRole:      "0004",
+		},
+		{
+			name:  "group mapped to an unknown role gets the default role",
+			login: "alan", password: "alan-password",
+			defaultRoleId: "0004",
+			wantRole:      "0004",
+		},
+		{
+			name:  "new user without a role",
+			login: "grace", password: "grace-password",
+			wantErr: errNoDirectoryRole,
+		},
+		{
+			name:  "existing local user is linked and gets the mapped role",
+			login: "ada", password: "ada-password",
+			localUser: "0004",
+			wantRole:  "0003",
+		},
+		{
+			name:  "existing local user keeps their role without a mapped group",
+			login: "grace", password: "grace-password",
+			localUser: "0002",
+			wantRole:  "0002",
+		},
+		{
+			name:  "wrong directory password never falls back to the local password",
+			login: "ada", password: "local-password",
+			fallbackToLocal: true,
+			localUser:       "0003",
+			wantErr:         ldapauth.ErrInvalidCredentials,
+		},
+		{
+			name:  "unknown directory user falls back to local login",
+			login: "linus@univ.edu", password: "local-password",
+			fallbackToLocal: true,
+			wantErr:         ldapauth.ErrUserNotFound,
+			wantUseLocal:    true,
+		},
+		{
+			name:  "unknown directory user without fallback",
+			login: "linus@univ.edu", password: "local-password",
+			wantErr: ldapauth.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := newLDAPStandIn(t,
+				newDirectoryEntry("ada", "ada-password", "cn=staff,ou=groups,dc=univ,dc=edu", strings.ToUpper(testStudentsGroup)),
+				newDirectoryEntry("grace", "grace-password"),
+				newDirectoryEntry("alan", "alan-password", testTeachersGroup),
+			)
+			useDirectorySettings(t, tt.defaultRoleId, tt.fallbackToLocal)
+
+			fake, pbClient := newFakePocketBase(t)
+			for _, roleId := range []string{"0002", "0003", "0004"} {
+				fake.roles[roleId] = map[string]interface{}{}
+			}
+			email := strings.TrimSuffix(tt.login, "@univ.edu") + "@univ.edu"
+			localId := ""
+			if tt.localUser != "" {
+				localId = fake.addUser(email, "local-password", tt.localUser)
+			}
+
+			token, useLocal, err := loginWithDirectory(pbClient, newTestDirectory(standIn), tt.login, tt.password)
+
+			if useLocal != tt.wantUseLocal {
+				t.Errorf("useLocal = %v, want %v", useLocal, tt.wantUseLocal)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("loginWithDirectory() error = %v, want %v", err, tt.wantErr)
+				}
+				if token != "" {
+					t.Errorf("loginWithDirectory() issued a token on error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loginWithDirectory() error = %v", err)
+			}
+
+			user := fake.userByEmail(email)
+			if user.Id == "" {
+				t.Fatalf("no user synced for %s", email)
+			}
+			if localId != "" && user.Id != localId {
+				t.Errorf("a new user %s was created instead of linking %s", user.Id, localId)
+			}
+			if user.RoleId != tt.wantRole {
+				t.Errorf("role = %q, want %q", user.RoleId, tt.wantRole)
+			}
+			if !strings.HasSuffix(user.Name, "Lovelace") {
+				t.Errorf("name = %q, want the directory name", user.Name)
+			}
+			if fake.issued[user.Id] != 1 {
+				t.Errorf("%d tokens issued for %s, want 1", fake.issued[user.Id], user.Id)
+			}
+
+			// The next login finds the user through the directory link instead of the email
+			linked := 0
+			for _, externalAuth := range fake.externalAuths {
+				if externalAuth.Provider == ldapProviderName && externalAuth.RecordRef == user.Id {
+					linked++
+				}
+			}
+			if linked != 1 {
+				t.Errorf("user linked %d times, want 1", linked)
+			}
+		})
+	}
+}
+
+func TestAccountLoginFallsBackToLocalPassword(t *testing.T) {
+	standIn := newLDAPStandIn(t, newDirectoryEntry("ada", "ada-password", testStudentsGroup))
+
+	for _, fallbackToLocal := range []bool{true, false} {
+		t.Run(fmt.Sprintf("fallback_to_local=%v", fallbackToLocal), func(t *testing.T) {
+			useDirectorySettings(t, "", fallbackToLocal)
+			fake, pbClient := newFakePocketBase(t)
+			fake.roles["0003"] = map[string]interface{}{}
+			fake.addUser("linus@univ.edu", "local-password", "0003")
+
+			body, _ := json.Marshal(map[string]string{"email": "linus@univ.edu", "password": "local-password"})
+			req := httptest.NewRequest(http.MethodPost, "/login/account", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			HandleAccountLogin(rec, req, pbClient, newTestDirectory(standIn), auth.NewLoginGuard(), nil, auth.NewSessionRegistry(pbClient))
+
+			want := http.StatusUnauthorized
+			if fallbackToLocal {
+				want = http.StatusOK
+			}
+			if rec.Code != want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, want, strings.TrimSpace(rec.Body.String()))
+			}
+		})
+	}
+}
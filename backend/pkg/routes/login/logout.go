@@ -0,0 +1,56 @@
+package login
+
+import (
+	"alphalabz/pkg/auth"
+	"encoding/json"
+	"net/http"
+)
+
+// Logout
+// Revokes the token used for the request and ends its session, so it can no longer be used, even before it expires.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Logged out successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → The request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Failure revoking the token.
+func HandleLogout(w http.ResponseWriter, r *http.Request, rl *auth.RevocationList, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens are revoked through /user/tokens", http.StatusForbidden)
+		return
+	}
+
+	if err := rl.Revoke(principal.Token); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	if err := sr.EndToken(principal.Token); err != nil {
+		http.Error(w, "Failed to end session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
+}
@@ -0,0 +1,289 @@
+package login
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/mfa"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/smtp"
+	"alphalabz/pkg/tools"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaMaxAttempts is the number of wrong codes accepted before the challenge is dropped.
+	mfaMaxAttempts = 5
+)
+
+// mfaChallenge holds a PocketBase token until the second factor is verified.
+type mfaChallenge struct {
+	mu     sync.Mutex
+	UserId string
+	// Email is the login name used for the password step, failed codes count against it.
+	Email    string
+	Token    string
+	Attempts int
+	// PendingSecret is set when the user has to enroll before the token is released.
+	PendingSecret string
+}
+
+// mfaChallenges maps challenge tokens to pending logins.
+var mfaChallenges = cache.New(mfaChallengeTTL, time.Minute)
+
+type mfaChallengeRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// startMFAChallenge checks whether the user needs a second factor and, if so, parks the token behind a challenge.
+//
+// It returns an empty challenge if the token can be released right away.
+func startMFAChallenge(pbClient *pocketbase.PocketBaseClient, token, email string) (challenge string, enroll bool, err error) {
+	userId, err := tools.GetUserIdFromJWT(token)
+	if err != nil {
+		return "", false, err
+	}
+
+	record, err := pbClient.GetUserMFA(userId)
+	if err != nil {
+		return "", false, err
+	}
+
+	if !record.Enabled {
+		userInfo, err := pbClient.ViewUser(userId)
+		if err != nil {
+			return "", false, err
+		}
+
+		required, err := mfa.Required(pbClient, userInfo.RoleId)
+		if err != nil || !required {
+			return "", false, err
+		}
+		enroll = true
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", false, err
+	}
+	challenge = hex.EncodeToString(raw)
+
+	mfaChallenges.Set(challenge, &mfaChallenge{UserId: userId, Email: email, Token: token}, cache.DefaultExpiration)
+	return challenge, enroll, nil
+}
+
+// Enroll TOTP During Login
+// Used when the role of the user enforces MFA but the user has no authenticator enrolled yet.
+// Returns a new TOTP secret, the login is completed by sending a code for it to `/login/mfa`.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "challenge": "challenge-from-login"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "secret": "BASE32SECRET",
+//	    "uri": "otpauth://totp/AlphaLabz:user@example.com?..."
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON.
+//   - 401 Unauthorized → Unknown or expired challenge.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 409 Conflict → The user is already enrolled.
+//   - 500 Internal Server Error → Server issue.
+func HandleMFAEnroll(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request mfaChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cached, found := mfaChallenges.Get(request.Challenge)
+	if !found {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	challenge := cached.(*mfaChallenge)
+
+	record, err := pbClient.GetUserMFA(challenge.UserId)
+	if err != nil {
+		http.Error(w, "Failed to load MFA settings", http.StatusInternalServerError)
+		return
+	}
+	if record.Enabled {
+		http.Error(w, "MFA is already enrolled", http.StatusConflict)
+		return
+	}
+
+	userInfo, err := pbClient.ViewUser(challenge.UserId)
+	if err != nil {
+		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		http.Error(w, "Failed to generate secret", http.StatusInternalServerError)
+		return
+	}
+
+	challenge.mu.Lock()
+	challenge.PendingSecret = secret
+	challenge.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret": secret,
+		"uri":    mfa.ProvisioningURI(mfa.Issuer, userInfo.Email, secret),
+	})
+}
+
+// Complete Login with a Second Factor
+// Verifies a TOTP or recovery code for a login challenge and releases the token.
+// When the challenge went through `/login/mfa/enroll`, the code confirms the new authenticator
+// and the recovery codes are returned once.
+// Wrong codes count as failed logins of the account, like wrong passwords.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "challenge": "challenge-from-login",
+//	    "code": "123456"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//			"status": "success",
+//			"timestamp": "2025-01-30 17:23:01",
+//		    "token": "your-auth-token",
+//		    "recovery_codes": ["abcde-12345", ...] (only after enrollment)
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON or the user still has to enroll.
+//   - 401 Unauthorized → Unknown or expired challenge, or invalid code.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 429 Too Many Requests → Backoff or lockout in effect, see the `Retry-After` header.
+//   - 500 Internal Server Error → Server issue.
+func HandleMFAVerify(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, lg *auth.LoginGuard, sc *smtp.SMTPClient, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request mfaChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cached, found := mfaChallenges.Get(request.Challenge)
+	if !found {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	challenge := cached.(*mfaChallenge)
+
+	// Serialize attempts on the same challenge so the attempt counter cannot be raced
+	challenge.mu.Lock()
+	defer challenge.mu.Unlock()
+
+	clientIP := tools.ClientIP(r)
+	if wait := lg.RetryAfter(challenge.Email, clientIP); wait > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	record, err := pbClient.GetUserMFA(challenge.UserId)
+	if err != nil {
+		http.Error(w, "Failed to load MFA settings", http.StatusInternalServerError)
+		return
+	}
+
+	var valid bool
+	var recoveryCodes []string
+	if record.Enabled {
+		valid, err = mfa.Verify(pbClient, &record, request.Code)
+		if err != nil {
+			http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+			return
+		}
+	} else if challenge.PendingSecret != "" {
+		valid = mfa.ValidateUnusedCode(challenge.UserId, challenge.PendingSecret, request.Code)
+		if valid {
+			var hashes []string
+			recoveryCodes, hashes, err = mfa.GenerateRecoveryCodes()
+			if err != nil {
+				http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+				return
+			}
+
+			record.Secret = challenge.PendingSecret
+			record.Enabled = true
+			record.RecoveryCodes = hashes
+			if err := pbClient.SaveUserMFA(&record); err != nil {
+				http.Error(w, "Failed to save MFA settings", http.StatusInternalServerError)
+				return
+			}
+		}
+	} else {
+		http.Error(w, "MFA enrollment required, call /login/mfa/enroll first", http.StatusBadRequest)
+		return
+	}
+
+	if !valid {
+		if lg.Failure(challenge.Email, clientIP) {
+			go notifyAccountLocked(pbClient, sc, challenge.Email, clientIP)
+		}
+		challenge.Attempts++
+		if challenge.Attempts >= mfaMaxAttempts {
+			mfaChallenges.Delete(request.Challenge)
+		}
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	mfaChallenges.Delete(request.Challenge)
+	lg.Success(challenge.Email)
+
+	if _, err := sr.Register(challenge.Token, r); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"status":    "success",
+		"token":     challenge.Token,
+		"timestamp": tools.Timestamp(),
+	}
+	if recoveryCodes != nil {
+		response["recovery_codes"] = recoveryCodes
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
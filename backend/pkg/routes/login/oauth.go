@@ -0,0 +1,267 @@
+package login
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/oidc"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/settings"
+	"alphalabz/pkg/tools"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// oidcProviderName is the provider name used to link users in PocketBase's _externalAuths collection.
+const oidcProviderName = "oidc"
+
+// Start OAuth / OpenID Connect Login
+// Redirects the browser to the identity provider using the authorization-code flow with PKCE.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ Successful Response (302 Found):
+// Redirects to the identity provider's authorization endpoint.
+//
+// ❌ Error Responses:
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+//   - 500 Internal Server Error → Failure creating the login request.
+//   - 503 Service Unavailable → OIDC login is not configured.
+func HandleOAuthLogin(w http.ResponseWriter, r *http.Request, provider *oidc.Provider) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if provider == nil {
+		http.Error(w, "OAuth login is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	authUrl, err := provider.AuthCodeURL()
+	if err != nil {
+		http.Error(w, "Failed to create login request", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, authUrl, http.StatusFound)
+}
+
+// Complete OAuth / OpenID Connect Login
+// Exchanges the authorization code returned by the identity provider, maps the IdP account to a user
+// (by linked subject first, then by email) and issues a PocketBase token.
+// Unknown users are created with the configured default role when auto provisioning is enabled.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ Query Parameters:
+//   - `code` (string, required) → The authorization code returned by the identity provider.
+//   - `state` (string, required) → The state returned by the identity provider.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//			"status": "success",
+//			"timestamp": "2025-01-30 17:23:01",
+//		    "token": "your-auth-token"
+//	}
+//
+// ✅ Second Factor Required (200 OK), completed through `/login/mfa`:
+//
+//	{
+//			"status": "mfa_required",
+//			"timestamp": "2025-01-30 17:23:01",
+//		    "challenge": "challenge-token",
+//		    "enrollment_required": false
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Missing code or state, or the provider returned an error.
+//   - 401 Unauthorized → Unknown state, invalid ID token, missing claims, or an existing account matched by an email the provider did not verify.
+//   - 403 Forbidden → No matching user and auto provisioning is disabled, or account pending administrator approval or suspended.
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+//   - 500 Internal Server Error → Server issue or PocketBase failure.
+//   - 503 Service Unavailable → OIDC login is not configured.
+func HandleOAuthCallback(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, provider *oidc.Provider, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if provider == nil {
+		http.Error(w, "OAuth login is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		http.Error(w, "Identity provider error: "+errParam, http.StatusBadRequest)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	state := r.URL.Query().Get("state")
+	if code == "" || state == "" {
+		http.Error(w, "Missing code or state", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+	oidcSettings := settings.OIDC
+
+	claims, err := provider.Exchange(state, code)
+	if errors.Is(err, oidc.ErrUnknownState) {
+		http.Error(w, "Unknown or expired login request", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Println("OIDC exchange failed:", err)
+		http.Error(w, "Authentication failed", http.StatusUnauthorized)
+		return
+	}
+
+	subject := stringClaim(claims, oidcSettings.ClaimMapping.Subject, "sub")
+	email := strings.ToLower(stringClaim(claims, oidcSettings.ClaimMapping.Email, "email"))
+	name := stringClaim(claims, oidcSettings.ClaimMapping.Name, "name")
+	if subject == "" {
+		http.Error(w, "ID token is missing the subject claim", http.StatusUnauthorized)
+		return
+	}
+
+	// Find a user already linked to this IdP subject
+	userId, err := pbClient.FindExternalAuth(oidcProviderName, subject)
+	if err != nil {
+		http.Error(w, "Failed to look up linked account", http.StatusInternalServerError)
+		return
+	}
+
+	// Fall back to the email address, then link the subject for the next logins
+	if userId == "" {
+		if email == "" {
+			http.Error(w, "ID token is missing the email claim", http.StatusUnauthorized)
+			return
+		}
+
+		// Only trust the email for matching when the IdP says it is verified,
+		// a provider that does not send the claim could otherwise take over a local account
+		emailVerified := claims["email_verified"] == true
+
+		user, err := pbClient.FindUserByEmail(email)
+		if err != nil {
+			http.Error(w, "Failed to look up user", http.StatusInternalServerError)
+			return
+		}
+		if user.Id != "" && !emailVerified {
+			http.Error(w, "Email address is not verified by the identity provider", http.StatusUnauthorized)
+			return
+		}
+		userId = user.Id
+
+		if userId == "" {
+			if !oidcSettings.AutoProvision || oidcSettings.DefaultRoleId == "" {
+				http.Error(w, "No account found for this identity", http.StatusForbidden)
+				return
+			}
+
+			userId, err = provisionUser(pbClient, email, name, oidcSettings.DefaultRoleId, emailVerified)
+			if err != nil {
+				log.Println("OIDC provisioning failed:", err)
+				http.Error(w, "Failed to create user", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := pbClient.CreateExternalAuth(userId, oidcProviderName, subject); err != nil {
+			http.Error(w, "Failed to link account", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if refuseInactiveUser(w, pbClient, userId) {
+		return
+	}
+
+	token, err := pbClient.ImpersonateUser(userId, 0)
+	if err != nil {
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	// The IdP login is only the first factor, MFA is asked like for /login/account.
+	// The challenge is bound to the account email so that /login/mfa counts failures against it.
+	userInfo, err := pbClient.ViewUser(userId)
+	if err != nil {
+		http.Error(w, "Failed to retrieve user info", http.StatusInternalServerError)
+		return
+	}
+
+	challenge, enroll, err := startMFAChallenge(pbClient, token, userInfo.Email)
+	if err != nil {
+		http.Error(w, "Failed to check MFA settings", http.StatusInternalServerError)
+		return
+	}
+
+	if challenge != "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":              "mfa_required",
+			"challenge":           challenge,
+			"enrollment_required": enroll,
+			"timestamp":           tools.Timestamp(),
+		})
+		return
+	}
+
+	if _, err := sr.Register(token, r); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"token":     token,
+		"timestamp": tools.Timestamp(),
+	})
+}
+
+// provisionUser creates a user for an IdP account. The random password is never shown, the user logs in through the IdP.
+// verified marks the email address as confirmed when the provider vouches for it.
+func provisionUser(pbClient *pocketbase.PocketBaseClient, email, name, roleId string, verified bool) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	password := base64.RawURLEncoding.EncodeToString(buf)
+
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+
+	userId, err := pbClient.NewUser(email, password, password, name, "", "", roleId, "")
+	if err != nil || !verified {
+		return userId, err
+	}
+
+	if err := pbClient.UpdateProfile(userId, pocketbase.User{Verified: true}); err != nil {
+		return userId, fmt.Errorf("failed to mark email as verified: %w", err)
+	}
+
+	return userId, nil
+}
+
+// stringClaim reads a string claim by its mapped name, falling back to the standard claim name.
+func stringClaim(claims map[string]interface{}, mapped, fallback string) string {
+	name := mapped
+	if name == "" {
+		name = fallback
+	}
+	value, _ := claims[name].(string)
+	return value
+}
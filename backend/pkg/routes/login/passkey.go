@@ -0,0 +1,137 @@
+package login
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/passkey"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type passkeyFinishRequest struct {
+	Ceremony   string          `json:"ceremony"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+// Start Passkey Login
+// Returns the WebAuthn options to pass to `navigator.credentials.get()`.
+// Any passkey registered for this site can answer, the user does not type an email address.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "ceremony": "ceremony-id",
+//	    "options": { "publicKey": { "challenge": "...", "rpId": "alphalabz.net", "userVerification": "required" } }
+//	}
+//
+// ❌ Error Responses:
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue.
+//   - 503 Service Unavailable → Passkey login is not configured.
+func HandlePasskeyLoginBegin(w http.ResponseWriter, r *http.Request, service *passkey.Service) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if service == nil {
+		http.Error(w, "Passkey login is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ceremony, options, err := service.BeginLogin()
+	if err != nil {
+		http.Error(w, "Failed to start passkey login", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ceremony": ceremony,
+		"options":  options,
+	})
+}
+
+// Complete Passkey Login
+// Verifies the assertion returned by `navigator.credentials.get()` and issues a PocketBase token.
+// The passkey requires user verification (PIN or biometrics), so it also satisfies roles that enforce MFA.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "ceremony": "ceremony-id",
+//	    "credential": { "id": "...", "rawId": "...", "type": "public-key", "response": { ... } }
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//			"status": "success",
+//			"timestamp": "2025-01-30 17:23:01",
+//		    "token": "your-auth-token"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON.
+//   - 401 Unauthorized → Unknown ceremony, or the assertion could not be verified.
+//   - 403 Forbidden → Account pending administrator approval or suspended.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue or PocketBase failure.
+//   - 503 Service Unavailable → Passkey login is not configured.
+func HandlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, service *passkey.Service, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if service == nil {
+		http.Error(w, "Passkey login is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var finishRequest passkeyFinishRequest
+	if err := json.NewDecoder(r.Body).Decode(&finishRequest); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userId, err := service.FinishLogin(finishRequest.Ceremony, bytes.NewReader(finishRequest.Credential))
+	if errors.Is(err, passkey.ErrUnknownCeremony) {
+		http.Error(w, "Unknown or expired login request", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Println("Passkey login failed:", err)
+		http.Error(w, "Authentication failed", http.StatusUnauthorized)
+		return
+	}
+
+	if refuseInactiveUser(w, pbClient, userId) {
+		return
+	}
+
+	token, err := pbClient.ImpersonateUser(userId, 0)
+	if err != nil {
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := sr.Register(token, r); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"token":     token,
+		"timestamp": tools.Timestamp(),
+	})
+}
@@ -0,0 +1,279 @@
+package login
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/patrickmn/go-cache"
+)
+
+// fakeUser is a user record of the fake PocketBase.
+type fakeUser struct {
+	pocketbase.User
+	Password string
+}
+
+// fakePocketBase is an in-memory stand-in for the PocketBase collections the login handlers use.
+type fakePocketBase struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu            sync.Mutex
+	users         map[string]*fakeUser
+	roles         map[string]map[string]interface{} // role id -> permissions document
+	externalAuths []pocketbase.ExternalAuth
+	mfa           map[string]pocketbase.UserMFA // user id -> MFA record
+	sessions      []pocketbase.Session
+	issued        map[string]int // user id -> impersonation tokens issued
+	nextId        int
+}
+
+var filterValue = regexp.MustCompile(`(\w+)='((?:[^'\\]|\\.)*)'`)
+
+// filterValues reads the field='value' conditions of a PocketBase filter.
+func filterValues(filter string) map[string]string {
+	values := map[string]string{}
+	for _, match := range filterValue.FindAllStringSubmatch(filter, -1) {
+		values[match[1]] = strings.ReplaceAll(match[2], `\'`, "'")
+	}
+	return values
+}
+
+func newFakePocketBase(t *testing.T) (*fakePocketBase, *pocketbase.PocketBaseClient) {
+	t.Helper()
+
+	fake := &fakePocketBase{
+		t:      t,
+		users:  map[string]*fakeUser{},
+		roles:  map[string]map[string]interface{}{},
+		mfa:    map[string]pocketbase.UserMFA{},
+		issued: map[string]int{},
+	}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.server.Close)
+
+	pbClient := &pocketbase.PocketBaseClient{
+		BaseURL:           fake.server.URL,
+		SuperToken:        "super-token",
+		HTTPClient:        fake.server.Client(),
+		UserInfoCache:     cache.New(time.Minute, time.Minute),
+		TokenCache:        cache.New(time.Minute, time.Minute),
+		UsersCollectionId: "_pb_users_auth_",
+	}
+	return fake, pbClient
+}
+
+func (fake *fakePocketBase) newId(prefix string) string {
+	fake.nextId++
+	return fmt.Sprintf("%s%011d", prefix, fake.nextId)
+}
+
+// addUser stores a user and returns its id.
+func (fake *fakePocketBase) addUser(email, password, roleId string) string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	id := fake.newId("u")
+	fake.users[id] = &fakeUser{User: pocketbase.User{Id: id, Email: email, Name: email, RoleId: roleId}, Password: password}
+	return id
+}
+
+func (fake *fakePocketBase) user(id string) pocketbase.User {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	if user, ok := fake.users[id]; ok {
+		return user.User
+	}
+	return pocketbase.User{}
+}
+
+func (fake *fakePocketBase) userByEmail(email string) pocketbase.User {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	for _, user := range fake.users {
+		if user.Email == email {
+			return user.User
+		}
+	}
+	return pocketbase.User{}
+}
+
+// token issues a user token like PocketBase does, the signature is not checked by the handlers under test.
+func (fake *fakePocketBase) token(userId string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":           userId,
+		"type":         "auth",
+		"collectionId": "_pb_users_auth_",
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"refreshable":  false,
+	})
+	signed, _ := token.SignedString([]byte("pocketbase-signing-key"))
+	return signed
+}
+
+func (fake *fakePocketBase) serve(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/collections/")
+	filter := filterValues(r.URL.Query().Get("filter"))
+
+	var body map[string]interface{}
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	str := func(key string) string {
+		value, _ := body[key].(string)
+		return value
+	}
+
+	switch {
+	case r.Method == http.MethodPost && path == "users/auth-with-password":
+		for _, user := range fake.users {
+			if user.Email == str("identity") && user.Password != "" && user.Password == str("password") {
+				json.NewEncoder(w).Encode(map[string]string{"token": fake.token(user.Id)})
+				return
+			}
+		}
+		http.Error(w, `{"message":"Failed to authenticate."}`, http.StatusBadRequest)
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "users/impersonate/"):
+		userId := strings.TrimPrefix(path, "users/impersonate/")
+		if _, ok := fake.users[userId]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fake.issued[userId]++
+		json.NewEncoder(w).Encode(map[string]string{"token": fake.token(userId)})
+
+	case r.Method == http.MethodGet && path == "users/records":
+		items := []pocketbase.User{}
+		for _, user := range fake.users {
+			if email, ok := filter["email"]; ok && user.Email != email {
+				continue
+			}
+			items = append(items, user.User)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items, "totalItems": len(items)})
+
+	case r.Method == http.MethodPost && path == "users/records":
+		id := fake.newId("u")
+		fake.users[id] = &fakeUser{
+			User:     pocketbase.User{Id: id, Email: str("email"), Name: str("name"), RoleId: str("role"), Status: str("status")},
+			Password: str("password"),
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": id})
+
+	case strings.HasPrefix(path, "users/records/"):
+		user, ok := fake.users[strings.TrimPrefix(path, "users/records/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPatch {
+			var update pocketbase.User
+			encoded, _ := json.Marshal(body)
+			json.Unmarshal(encoded, &update)
+			if update.Email != "" {
+				user.Email = update.Email
+			}
+			if update.Name != "" {
+				user.Name = update.Name
+			}
+			if update.RoleId != "" {
+				user.RoleId = update.RoleId
+			}
+			if update.Verified {
+				user.Verified = true
+			}
+		}
+		json.NewEncoder(w).Encode(user.User)
+
+	case r.Method == http.MethodPost && path == "user_settings/records":
+		json.NewEncoder(w).Encode(map[string]string{"id": fake.newId("s")})
+
+	case r.Method == http.MethodGet && path == "roles/records":
+		items := []map[string]interface{}{}
+		for id, permissions := range fake.roles {
+			items = append(items, map[string]interface{}{"id": id, "permissions": permissions})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "roles/records/"):
+		id := strings.TrimPrefix(path, "roles/records/")
+		permissions, ok := fake.roles[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "permissions": permissions})
+
+	case r.Method == http.MethodGet && path == "_externalAuths/records":
+		items := []pocketbase.ExternalAuth{}
+		for _, externalAuth := range fake.externalAuths {
+			if externalAuth.Provider == filter["provider"] && externalAuth.ProviderId == filter["providerId"] {
+				items = append(items, externalAuth)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+
+	case r.Method == http.MethodPost && path == "_externalAuths/records":
+		externalAuth := pocketbase.ExternalAuth{
+			Id:            fake.newId("e"),
+			CollectionRef: str("collectionRef"),
+			RecordRef:     str("recordRef"),
+			Provider:      str("provider"),
+			ProviderId:    str("providerId"),
+		}
+		fake.externalAuths = append(fake.externalAuths, externalAuth)
+		json.NewEncoder(w).Encode(externalAuth)
+
+	case r.Method == http.MethodGet && path == "user_mfa/records":
+		items := []pocketbase.UserMFA{}
+		if record, ok := fake.mfa[filter["user"]]; ok {
+			items = append(items, record)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+
+	case r.Method == http.MethodPost && path == "sessions/records":
+		session := pocketbase.Session{Id: fake.newId("x"), UserId: str("user"), TokenHash: str("token_hash"), ImpersonatorId: str("impersonator")}
+		fake.sessions = append(fake.sessions, session)
+		json.NewEncoder(w).Encode(session)
+
+	default:
+		fake.t.Errorf("unexpected PocketBase request: %s %s", r.Method, r.URL)
+		http.NotFound(w, r)
+	}
+}
+
+// useSettings makes the handlers read the given settings.yml content, they load it from the working directory.
+func useSettings(t *testing.T, content string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "settings.yml"), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
@@ -0,0 +1,89 @@
+package login
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Refresh the Login Token
+// Exchanges the current token for a new one before it expires. The old token is revoked and its session replaced.
+// Tokens of SSO, LDAP and passkey logins are issued through impersonation, which PocketBase does not refresh,
+// so a new token is issued for them instead.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//			"status": "success",
+//			"timestamp": "2025-01-30 17:23:01",
+//		    "token": "your-new-auth-token"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → The token is not refreshable (a personal access token or an impersonation token).
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue or PocketBase failure.
+func HandleTokenRefresh(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, rl *auth.RevocationList, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot be refreshed", http.StatusForbidden)
+		return
+	}
+
+	// Admins impersonating a user must start a new impersonation instead
+	if principal.IsImpersonated() {
+		http.Error(w, "Token is not refreshable", http.StatusForbidden)
+		return
+	}
+
+	token, _, err := pbClient.AuthRefresh(principal.Token)
+	if errors.Is(err, pocketbase.ErrTokenNotRefreshable) {
+		// The token was verified by the auth middleware, it only comes from a login that PocketBase cannot renew
+		token, err = pbClient.ImpersonateUser(principal.UserId, 0)
+	}
+	if err != nil {
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := sr.Register(token, r); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	// The old token must not stay usable next to the new one
+	if err := rl.Revoke(principal.Token); err != nil {
+		http.Error(w, "Failed to revoke old token", http.StatusInternalServerError)
+		return
+	}
+
+	if err := sr.EndToken(principal.Token); err != nil {
+		http.Error(w, "Failed to end old session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"token":     token,
+		"timestamp": tools.Timestamp(),
+	})
+}
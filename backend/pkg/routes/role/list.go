@@ -1,9 +1,9 @@
 package role
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"net/http"
 )
@@ -58,22 +58,13 @@ func HandleRoleList(w http.ResponseWriter, r *http.Request, pbClient *pocketbase
 		return
 	}
 
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	scopes, err := ce.ScopeFetcher(pbClient, userId, casbin.PermissionConfig{
-		Resources: "roles",
-		Actions:   "list",
-	})
+	scopes, err := principal.FetchScopes(ce, pbClient, "roles", "list")
 	if err != nil {
 		http.Error(w, "Failed to fetch user permissions", http.StatusInternalServerError)
 		return
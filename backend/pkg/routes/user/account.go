@@ -0,0 +1,594 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/password"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/settings"
+	"alphalabz/pkg/smtp"
+	"alphalabz/pkg/tools"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	passwordResetPurpose = "password_reset"
+	passwordResetTTL     = 30 * time.Minute
+	emailChangePurpose   = "email_change"
+	emailChangeTTL       = 24 * time.Hour
+)
+
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type passwordResetConfirm struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	PasswordConfirm string `json:"passwordConfirm"`
+}
+
+type passwordChangeRequest struct {
+	OldPassword     string `json:"oldPassword"`
+	Password        string `json:"password"`
+	PasswordConfirm string `json:"passwordConfirm"`
+}
+
+type emailChangeRequest struct {
+	Email string `json:"email"`
+}
+
+type emailChangeConfirm struct {
+	Token string `json:"token"`
+}
+
+// Request a Password Reset
+// Sends a single-use password reset link to the email address if it belongs to a user.
+// The response is the same whether the address is known or not.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "email": "user@example.com"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "If the address belongs to an account, a reset link has been sent"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON or missing email.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandlePasswordResetRequest(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, sc *smtp.SMTPClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var resetRequest passwordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&resetRequest); err != nil || resetRequest.Email == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := pbClient.FindUserByEmail(strings.ToLower(resetRequest.Email))
+	if err != nil {
+		http.Error(w, "Failed to look up user", http.StatusInternalServerError)
+		return
+	}
+
+	if user.Id != "" {
+		state, err := actionTokenState(pbClient, user.Id)
+		if err != nil {
+			http.Error(w, "Failed to generate reset token", http.StatusInternalServerError)
+			return
+		}
+
+		token, err := tools.SignActionToken(settings.JWTSecret, passwordResetPurpose, map[string]interface{}{
+			"user_id": user.Id,
+			"state":   state,
+		}, passwordResetTTL)
+		if err != nil {
+			http.Error(w, "Failed to generate reset token", http.StatusInternalServerError)
+			return
+		}
+
+		body, err := smtp.RenderTemplate("password_reset.html", map[string]string{
+			"Name":      user.Name,
+			"Link":      fmt.Sprintf("%s/reset-password?token=%s", settings.AppUrl, url.QueryEscape(token)),
+			"ExpiresIn": "30 minutes",
+		})
+		if err != nil {
+			http.Error(w, "Failed to render email", http.StatusInternalServerError)
+			return
+		}
+
+		// Do not reveal delivery failures, they would tell the caller the account exists
+		if _, err := sc.SendMail("Reset your AlphaLabz password", body, user.Email); err != nil {
+			log.Println("Failed to send password reset email:", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the address belongs to an account, a reset link has been sent"})
+}
+
+// Confirm a Password Reset
+// Sets a new password using the token received by email. Each token can only be used once.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "token": "reset-token",
+//	    "password": "newPassword",
+//	    "passwordConfirm": "newPassword"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Password reset successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, missing fields, passwords do not match, password refused by the policy, or invalid / used token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue or failure updating the password.
+func HandlePasswordResetConfirm(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var confirm passwordResetConfirm
+	if err := json.NewDecoder(r.Body).Decode(&confirm); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if confirm.Token == "" || confirm.Password == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if confirm.Password != confirm.PasswordConfirm {
+		http.Error(w, "Passwords do not match", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	claims, err := tools.ParseActionToken(settings.JWTSecret, passwordResetPurpose, confirm.Token)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	userId, _ := claims["user_id"].(string)
+	if userId == "" {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	userInfo, err := pbClient.ViewUser(userId)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	// Check the policy before using up the token, so the user can pick another password
+	if rejectWeakPassword(w, confirm.Password, userInfo.Email, userInfo.Name) {
+		return
+	}
+
+	// The token is used up by the update, check it and update under the lock
+	actionTokenUses.Lock()
+	defer actionTokenUses.Unlock()
+
+	if ok, err := actionTokenUnused(pbClient, claims, userId); err != nil {
+		http.Error(w, "Failed to check token", http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	if err := pbClient.UpdatePassword(userId, confirm.Password); err != nil {
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successfully"})
+}
+
+// Change Password
+// Only users with the update:"own" permission on the "users" resource can change their password.
+// The current password is required. Every existing token of the user stops working afterwards.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `PATCH`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "oldPassword": "currentPassword",
+//	    "password": "newPassword",
+//	    "passwordConfirm": "newPassword"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Password changed successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, missing fields, passwords do not match or password refused by the policy.
+//   - 401 Unauthorized → Missing or invalid token, or wrong current password.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only PATCH is allowed).
+//   - 429 Too Many Requests → Backoff or lockout in effect, see the `Retry-After` header.
+//   - 500 Internal Server Error → Server issue or failure updating the password.
+func HandleChangePassword(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, lg *auth.LoginGuard) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot change the password", http.StatusForbidden)
+		return
+	}
+
+	var changeRequest passwordChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&changeRequest); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if changeRequest.OldPassword == "" || changeRequest.Password == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if changeRequest.Password != changeRequest.PasswordConfirm {
+		http.Error(w, "Passwords do not match", http.StatusBadRequest)
+		return
+	}
+
+	userInfo, err := pbClient.ViewUser(principal.UserId)
+	if err != nil {
+		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
+		return
+	}
+
+	// The current password check counts towards the same backoff and lockout as /login/account
+	clientIP := tools.ClientIP(r)
+	if wait := lg.RetryAfter(userInfo.Email, clientIP); wait > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// Verify the current password with PocketBase
+	if _, err := pbClient.AuthUserWithPassword(userInfo.Email, changeRequest.OldPassword); err != nil {
+		lg.Failure(userInfo.Email, clientIP)
+		http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+		return
+	}
+	lg.Success(userInfo.Email)
+
+	if rejectWeakPassword(w, changeRequest.Password, userInfo.Email, userInfo.Name) {
+		return
+	}
+
+	if err := pbClient.UpdatePassword(principal.UserId, changeRequest.Password); err != nil {
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully"})
+}
+
+// Change Email
+// Only users with the update:"own" permission on the "users" resource can change their email address.
+// A confirmation link is sent to the new address, the email is only updated once the link is confirmed.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `PATCH`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "email": "new@example.com"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Confirmation email sent to the new address"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON or invalid email address.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only PATCH is allowed).
+//   - 409 Conflict → The email address is already used.
+//   - 500 Internal Server Error → Server issue or failure sending the email.
+func HandleChangeEmail(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sc *smtp.SMTPClient) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot change the email address", http.StatusForbidden)
+		return
+	}
+
+	var changeRequest emailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&changeRequest); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	newEmail := strings.ToLower(strings.TrimSpace(changeRequest.Email))
+	if address, err := mail.ParseAddress(newEmail); err != nil || address.Address != newEmail {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	existingUser, err := pbClient.FindUserByEmail(newEmail)
+	if err != nil {
+		http.Error(w, "Failed to look up user", http.StatusInternalServerError)
+		return
+	}
+	if existingUser.Id != "" {
+		http.Error(w, "Email address already in use", http.StatusConflict)
+		return
+	}
+
+	userInfo, err := pbClient.ViewUser(principal.UserId)
+	if err != nil {
+		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	state, err := actionTokenState(pbClient, principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to generate confirmation token", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := tools.SignActionToken(settings.JWTSecret, emailChangePurpose, map[string]interface{}{
+		"user_id": principal.UserId,
+		"email":   newEmail,
+		"state":   state,
+	}, emailChangeTTL)
+	if err != nil {
+		http.Error(w, "Failed to generate confirmation token", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := smtp.RenderTemplate("email_change.html", map[string]string{
+		"Name":      userInfo.Name,
+		"Link":      fmt.Sprintf("%s/confirm-email?token=%s", settings.AppUrl, url.QueryEscape(token)),
+		"ExpiresIn": "24 hours",
+	})
+	if err != nil {
+		http.Error(w, "Failed to render email", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := sc.SendMail("Confirm your new AlphaLabz email address", body, newEmail); err != nil {
+		http.Error(w, "Failed to send confirmation email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Confirmation email sent to the new address"})
+}
+
+// Confirm Email Change
+// Updates the user's email address using the token sent to the new address. Each token can only be used once.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "token": "confirmation-token"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Email changed successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON or invalid / used token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 409 Conflict → The email address was taken in the meantime.
+//   - 500 Internal Server Error → Server issue or failure updating the email.
+func HandleConfirmEmailChange(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var confirm emailChangeConfirm
+	if err := json.NewDecoder(r.Body).Decode(&confirm); err != nil || confirm.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	claims, err := tools.ParseActionToken(settings.JWTSecret, emailChangePurpose, confirm.Token)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	userId, _ := claims["user_id"].(string)
+	newEmail, _ := claims["email"].(string)
+	if userId == "" || newEmail == "" {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	existingUser, err := pbClient.FindUserByEmail(newEmail)
+	if err != nil {
+		http.Error(w, "Failed to look up user", http.StatusInternalServerError)
+		return
+	}
+	if existingUser.Id != "" && existingUser.Id != userId {
+		http.Error(w, "Email address already in use", http.StatusConflict)
+		return
+	}
+
+	// The token is used up by the update, check it and update under the lock
+	actionTokenUses.Lock()
+	defer actionTokenUses.Unlock()
+
+	if ok, err := actionTokenUnused(pbClient, claims, userId); err != nil {
+		http.Error(w, "Failed to check token", http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	// The link reached the new address, so it is verified as well
+	if err := pbClient.UpdateProfile(userId, pocketbase.User{Email: newEmail, Verified: true}); err != nil {
+		http.Error(w, "Failed to change email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email changed successfully"})
+}
+
+// actionTokenUses serializes the check-and-use of password reset and email change tokens, so that concurrent
+// requests with the same token cannot both see it unused.
+var actionTokenUses sync.Mutex
+
+// actionTokenState fingerprints the credentials of a user, for the "state" claim of password reset and email change tokens.
+// It hashes the PocketBase token key, which changes with the password and the email address, so using a token
+// (or changing the password or email in another way) invalidates it without keeping track of used tokens.
+func actionTokenState(pbClient *pocketbase.PocketBaseClient, userId string) (string, error) {
+	tokenKey, err := pbClient.UserTokenKey(userId)
+	if err != nil {
+		return "", err
+	}
+	return tools.HashToken(tokenKey), nil
+}
+
+// actionTokenUnused reports whether the credentials of the user are still those the token was issued for.
+func actionTokenUnused(pbClient *pocketbase.PocketBaseClient, claims jwt.MapClaims, userId string) (bool, error) {
+	issuedFor, _ := claims["state"].(string)
+	if issuedFor == "" {
+		return false, nil
+	}
+
+	state, err := actionTokenState(pbClient, userId)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(state), []byte(issuedFor)) == 1, nil
+}
+
+// rejectWeakPassword checks a new password against the PasswordPolicy of settings.yml.
+// personalInfo holds the email address and names of the user.
+//
+// It writes 400 Bad Request with the reason (or 500 if the check failed) and returns true when the password is refused.
+func rejectWeakPassword(w http.ResponseWriter, newPassword string, personalInfo ...string) bool {
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return true
+	}
+
+	policy := password.Policy{
+		MinLength:          settings.PasswordPolicy.MinLength,
+		RequireUppercase:   settings.PasswordPolicy.RequireUppercase,
+		RequireLowercase:   settings.PasswordPolicy.RequireLowercase,
+		RequireDigit:       settings.PasswordPolicy.RequireDigit,
+		RequireSymbol:      settings.PasswordPolicy.RequireSymbol,
+		ForbidPersonalInfo: settings.PasswordPolicy.ForbidPersonalInfo,
+		BreachedHashesDir:  settings.PasswordPolicy.BreachedHashesDir,
+	}
+
+	err = policy.Check(newPassword, personalInfo...)
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		http.Error(w, policyErr.Error(), http.StatusBadRequest)
+		return true
+	} else if err != nil {
+		log.Println("Failed to check password policy:", err)
+		http.Error(w, "Failed to check password", http.StatusInternalServerError)
+		return true
+	}
+
+	return false
+}
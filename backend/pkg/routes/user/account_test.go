@@ -0,0 +1,185 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/passkey"
+	"alphalabz/pkg/passkey/passkeytest"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// TestAccountManagementRefusesAPITokens checks that a personal access token, even one scoped users:update:own,
+// cannot take over the account: the handlers answer 403 before touching PocketBase.
+func TestAccountManagementRefusesAPITokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected PocketBase request: %s %s", r.Method, r.URL)
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	pbClient := &pocketbase.PocketBaseClient{BaseURL: server.URL, HTTPClient: server.Client()}
+
+	service, err := passkey.NewService(testRPId, "AlphaLabz", []string{testOrigin}, passkeytest.NewStore())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		handler func(w http.ResponseWriter, r *http.Request)
+	}{
+		{"change password", http.MethodPatch, `{"oldPassword": "old", "password": "new", "passwordConfirm": "new"}`, func(w http.ResponseWriter, r *http.Request) {
+			HandleChangePassword(w, r, pbClient, nil, auth.NewLoginGuard())
+		}},
+		{"change email", http.MethodPatch, `{"email": "attacker@example.com"}`, func(w http.ResponseWriter, r *http.Request) {
+			HandleChangeEmail(w, r, pbClient, nil, nil)
+		}},
+		{"enroll MFA", http.MethodPost, "", func(w http.ResponseWriter, r *http.Request) {
+			HandleMFAEnroll(w, r, pbClient, nil)
+		}},
+		{"verify MFA", http.MethodPost, `{"code": "123456"}`, func(w http.ResponseWriter, r *http.Request) {
+			HandleMFAVerify(w, r, pbClient, nil)
+		}},
+		{"regenerate recovery codes", http.MethodPost, `{"code": "123456"}`, func(w http.ResponseWriter, r *http.Request) {
+			HandleMFARecoveryCodes(w, r, pbClient, nil)
+		}},
+		{"disable MFA", http.MethodDelete, `{"code": "123456"}`, func(w http.ResponseWriter, r *http.Request) {
+			HandleMFADisable(w, r, pbClient, nil)
+		}},
+		{"end session", http.MethodDelete, "", func(w http.ResponseWriter, r *http.Request) {
+			HandleEndSession(w, r, "session0000001", pbClient, nil, nil)
+		}},
+		{"remove passkey", http.MethodDelete, "", func(w http.ResponseWriter, r *http.Request) {
+			HandleRemovePasskey(w, r, "credential", pbClient, nil, service)
+		}},
+	}
+
+	// A token scoped to exactly the permission the routes check
+	principal := &auth.Principal{UserId: testUserId, APITokenId: "token0000000001", Scopes: []string{"users:update:own"}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/user/account", strings.NewReader(tt.body))
+			req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+		})
+	}
+}
+
+// useSettings makes the handlers read the given settings.yml content, they load it from the working directory.
+func useSettings(t *testing.T, content string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "settings.yml"), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// TestPasswordResetConfirmIsSingleUse sends the same reset link many times at once: only one request may
+// change the password, however the requests interleave.
+func TestPasswordResetConfirmIsSingleUse(t *testing.T) {
+	const secret = "action-token-secret"
+	useSettings(t, "JWTSecret: "+secret+"\n")
+
+	// PocketBase rotates the token key of the user when the password changes
+	var mu sync.Mutex
+	tokenKey, updates := "token-key-1", 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/collections/users/records/"+testUserId {
+			t.Errorf("unexpected PocketBase request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			mu.Lock()
+			defer mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]string{"tokenKey": tokenKey})
+
+		case http.MethodPatch:
+			// Leave the other requests time to check the token before the key rotates
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			updates++
+			tokenKey = fmt.Sprintf("token-key-%d", updates+1)
+			json.NewEncoder(w).Encode(pocketbase.User{Id: testUserId})
+		}
+	}))
+	defer server.Close()
+
+	pbClient := &pocketbase.PocketBaseClient{
+		BaseURL:       server.URL,
+		HTTPClient:    server.Client(),
+		UserInfoCache: cache.New(time.Minute, time.Minute),
+		TokenCache:    cache.New(time.Minute, time.Minute),
+	}
+	pbClient.UserInfoCache.Set(testUserId, pocketbase.User{Id: testUserId, Email: "student@univ.edu"}, cache.DefaultExpiration)
+
+	token, err := tools.SignActionToken(secret, passwordResetPurpose, map[string]interface{}{
+		"user_id": testUserId,
+		"state":   tools.HashToken("token-key-1"),
+	}, time.Minute)
+	if err != nil {
+		t.Fatalf("SignActionToken() error = %v", err)
+	}
+	body := `{"token": "` + token + `", "password": "correct horse battery", "passwordConfirm": "correct horse battery"}`
+
+	const requests = 5
+	codes := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/user/account/password/reset/confirm", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			HandlePasswordResetConfirm(rec, req, pbClient)
+			codes[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			succeeded++
+		case http.StatusBadRequest:
+		default:
+			t.Errorf("status = %d, want 200 or 400", code)
+		}
+	}
+	if succeeded != 1 || updates != 1 {
+		t.Errorf("%d requests succeeded and %d updated the password, want 1", succeeded, updates)
+	}
+}
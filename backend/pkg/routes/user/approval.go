@@ -0,0 +1,269 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/settings"
+	"alphalabz/pkg/smtp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+type approvalRequest struct {
+	RoleId string `json:"role_id"`
+}
+
+// List Pending Registrations
+// Returns the self-registered users waiting for approval.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token and the create permission on the "users" resource.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "items": [
+//	        {
+//	            "id": "user123",
+//	            "email": "student@univ.edu",
+//	            "name": "Jane Doe",
+//	            "status": "pending",
+//	            "created": "2025-01-30 17:23:01.000Z"
+//	        }
+//	    ]
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to create users.
+//   - 405 Method Not Allowed → Request method is not GET.
+//   - 500 Internal Server Error → Server issue.
+func HandleListPendingUsers(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := approverScopes(w, r, pbClient, ce); !ok {
+		return
+	}
+
+	filter := url.QueryEscape(fmt.Sprintf("(status='%s')", pocketbase.UserStatusPending))
+	users, _, err := pbClient.ListUsers([]string{"id", "email", "name", "status", "created"}, nil, filter)
+	if err != nil {
+		http.Error(w, "Failed to list pending users", http.StatusInternalServerError)
+		return
+	}
+
+	if users == nil {
+		users = []pocketbase.User{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": users})
+}
+
+// Approve a Pending Registration
+// Activates a self-registered user with the given role and tells them by email.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token and the create permission on the "users" resource for the role.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the pending user.
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "role_id": "0003" // Allowed values depend on available roles, excluding "0001" and roles inheriting from it
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "User approved successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, missing or unknown role.
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not authorized to grant this role.
+//   - 404 Not Found → No pending user with this ID.
+//   - 405 Method Not Allowed → Request method is not POST.
+//   - 500 Internal Server Error → Server issue.
+func HandleApproveUser(w http.ResponseWriter, r *http.Request, userId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sc *smtp.SMTPClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scopes, ok := approverScopes(w, r, pbClient, ce)
+	if !ok {
+		return
+	}
+
+	var approval approvalRequest
+	if err := json.NewDecoder(r.Body).Decode(&approval); err != nil || approval.RoleId == "" {
+		http.Error(w, "role_id is required", http.StatusBadRequest)
+		return
+	}
+
+	roles, err := pbClient.ListRoles([]string{"id", "name", "type"}, "")
+	if err != nil {
+		http.Error(w, "Failed to get available roles", http.StatusInternalServerError)
+		return
+	}
+
+	switch err := checkGrantableRole(ce, approval.RoleId, roles, scopes); {
+	case errors.Is(err, errRoleNotFound):
+		http.Error(w, "Role not found", http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Unauthorized to grant this role", http.StatusForbidden)
+		return
+	}
+
+	pendingUser, ok := loadPendingUser(w, userId, pbClient)
+	if !ok {
+		return
+	}
+
+	if err := pbClient.UpdateProfile(pendingUser.Id, pocketbase.User{RoleId: approval.RoleId, Status: pocketbase.UserStatusActive}); err != nil {
+		http.Error(w, "Failed to approve user", http.StatusInternalServerError)
+		return
+	}
+
+	go notifyRegistrationDecision(sc, pendingUser, true)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User approved successfully"})
+}
+
+// Reject a Pending Registration
+// Deletes a self-registered user that was not approved, along with their settings, and tells them by email.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token and the create permission on the "users" resource.
+//
+// ✅ HTTP Method: `DELETE`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the pending user.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Registration rejected successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to create users.
+//   - 404 Not Found → No pending user with this ID.
+//   - 405 Method Not Allowed → Request method is not DELETE.
+//   - 500 Internal Server Error → Server issue.
+func HandleRejectUser(w http.ResponseWriter, r *http.Request, userId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sc *smtp.SMTPClient) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := approverScopes(w, r, pbClient, ce); !ok {
+		return
+	}
+
+	pendingUser, ok := loadPendingUser(w, userId, pbClient)
+	if !ok {
+		return
+	}
+
+	if err := pbClient.DeleteUser(pendingUser.Id); err != nil {
+		http.Error(w, "Failed to reject user", http.StatusInternalServerError)
+		return
+	}
+
+	if settingsId := pendingUser.SettingId; settingsId != "" {
+		if err := pbClient.DeleteUserSettings(settingsId); err != nil {
+			log.Printf("Failed to delete settings %s of user %s: %v", settingsId, pendingUser.Id, err)
+		}
+	}
+
+	go notifyRegistrationDecision(sc, pendingUser, false)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Registration rejected successfully"})
+}
+
+// approverScopes returns the caller's users:create scopes, writing the error response if they have none.
+func approverScopes(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) ([]string, bool) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	scopes, err := principal.FetchScopes(ce, pbClient, "users", "create")
+	if err != nil {
+		http.Error(w, "Failed to fetch user scopes", http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(scopes) == 0 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return scopes, true
+}
+
+// loadPendingUser loads a user waiting for approval, writing 404 Not Found if there is none with this ID.
+func loadPendingUser(w http.ResponseWriter, userId string, pbClient *pocketbase.PocketBaseClient) (pocketbase.User, bool) {
+	filter := url.QueryEscape(fmt.Sprintf("(id='%s' && status='%s')", pocketbase.EscapeFilterValue(userId), pocketbase.UserStatusPending))
+	users, _, err := pbClient.ListUsers([]string{"id", "email", "name", "status", "user_settings"}, nil, filter)
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return pocketbase.User{}, false
+	}
+	if len(users) == 0 {
+		http.Error(w, "Pending user not found", http.StatusNotFound)
+		return pocketbase.User{}, false
+	}
+
+	return users[0], true
+}
+
+// notifyRegistrationDecision tells a self-registered user whether their account was approved.
+func notifyRegistrationDecision(sc *smtp.SMTPClient, user pocketbase.User, approved bool) {
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		log.Println("Failed to load settings:", err)
+		return
+	}
+
+	body, err := smtp.RenderTemplate("registration_decision.html", map[string]interface{}{
+		"Name":     user.Name,
+		"Approved": approved,
+		"Link":     settings.AppUrl,
+	})
+	if err != nil {
+		log.Println("Failed to render registration email:", err)
+		return
+	}
+
+	subject := "Your AlphaLabz registration was not approved"
+	if approved {
+		subject = "Your AlphaLabz account is ready"
+	}
+
+	if _, err := sc.SendMail(subject, body, user.Email); err != nil {
+		log.Println("Failed to send registration email:", err)
+	}
+}
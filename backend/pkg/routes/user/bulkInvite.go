@@ -0,0 +1,220 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/smtp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxBulkInvites limits the number of rows of a single CSV upload.
+const maxBulkInvites = 500
+
+// Results of a bulk invitation row
+const (
+	bulkInviteCreated          = "created"
+	bulkInviteSkippedDuplicate = "skipped_duplicate"
+	bulkInviteForbiddenRole    = "forbidden_role"
+	bulkInviteUnknownRole      = "unknown_role"
+	bulkInviteInvalidEmail     = "invalid_email"
+	bulkInviteInvalidRow       = "invalid_row"
+	bulkInviteFailed           = "failed"
+)
+
+type bulkInviteResult struct {
+	Row          int    `json:"row"`
+	Email        string `json:"email"`
+	RoleId       string `json:"role_id"`
+	Result       string `json:"result"`
+	InvitationId string `json:"invitation_id,omitempty"`
+	EmailSent    *bool  `json:"email_sent,omitempty"`
+}
+
+// Invite Users from a CSV File
+// Sends an invitation for every row of the file, like `/user/invite`, and reports the result of each row.
+// Rows that fail do not stop the import.
+//
+// ✅ Authorization:
+// - Requires an `Authorization` header with a valid token.
+// - The requesting user must have permission to create users (determined via Casbin), each row's role is checked.
+//
+// ✅ Request Body:
+// - `Content-Type: multipart/form-data`
+// - Fields:
+//   - `file` (file, required) → CSV file with the columns `email,name,role_id[,group]`, at most 500 rows.
+//     A first row starting with "email" is treated as a header. `group` is the optional course or group.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "summary": {
+//	        "created": 1,
+//	        "skipped_duplicate": 1
+//	    },
+//	    "results": [
+//	        { "row": 2, "email": "jane@example.com", "role_id": "0003", "result": "created", "invitation_id": "invitation123", "email_sent": true },
+//	        { "row": 3, "email": "john@example.com", "role_id": "0003", "result": "skipped_duplicate" }
+//	    ]
+//	}
+//
+// Each row's `result` is one of "created", "skipped_duplicate", "forbidden_role", "unknown_role",
+// "invalid_email", "invalid_row" or "failed".
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Missing file, unreadable CSV or too many rows.
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to invite users.
+//   - 405 Method Not Allowed → Request method is not POST.
+//   - 500 Internal Server Error → Server issue.
+func HandleBulkInvite(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sc *smtp.SMTPClient) {
+	// Constrain request method
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Grant user scopes
+	scopes, err := principal.FetchScopes(ce, pbClient, "users", "create")
+	if err != nil {
+		http.Error(w, "Failed to fetch user scopes", http.StatusInternalServerError)
+		return
+	}
+	if len(scopes) == 0 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Constrain request size
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		http.Error(w, "Error parsing form data", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing CSV file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	rows, err := readInviteCSV(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get available roles
+	roles, err := pbClient.ListRoles([]string{"id", "name", "type"}, "")
+	if err != nil {
+		http.Error(w, "Failed to get available roles", http.StatusInternalServerError)
+		return
+	}
+
+	summary := map[string]int{}
+	results := make([]bulkInviteResult, 0, len(rows))
+	for _, row := range rows {
+		result := bulkInviteResult{Row: row.line, Email: row.invitee.Email, RoleId: row.invitee.RoleId}
+
+		if !row.valid {
+			result.Result = bulkInviteInvalidRow
+		} else {
+			invitation, _, emailSent, err := inviteUser(pbClient, ce, sc, principal.UserId, row.invitee, roles, scopes)
+			switch {
+			case err == nil:
+				result.Result = bulkInviteCreated
+				result.InvitationId = invitation.Id
+				result.EmailSent = &emailSent
+			case errors.Is(err, errInvalidEmail):
+				result.Result = bulkInviteInvalidEmail
+			case errors.Is(err, errRoleNotFound):
+				result.Result = bulkInviteUnknownRole
+			case errors.Is(err, errForbiddenRole):
+				result.Result = bulkInviteForbiddenRole
+			case errors.Is(err, errAlreadyInvited):
+				result.Result = bulkInviteSkippedDuplicate
+			default:
+				result.Result = bulkInviteFailed
+			}
+		}
+
+		summary[result.Result]++
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"summary": summary,
+		"results": results,
+	})
+}
+
+type inviteCSVRow struct {
+	line    int
+	invitee Invitee
+	valid   bool
+}
+
+// readInviteCSV reads the `email,name,role_id[,group]` rows of a bulk invitation file, skipping a header line.
+func readInviteCSV(file io.Reader) ([]inviteCSVRow, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []inviteCSVRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("invalid CSV file")
+		}
+
+		line, _ := reader.FieldPos(0)
+		if line == 1 && strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(record[0], "\ufeff")), "email") {
+			continue
+		}
+
+		if len(rows) == maxBulkInvites {
+			return nil, errors.New("too many rows, at most 500 invitations can be sent at once")
+		}
+
+		row := inviteCSVRow{line: line, valid: len(record) >= 3 && len(record) <= 4}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch i {
+			case 0:
+				row.invitee.Email = value
+			case 1:
+				row.invitee.Name = value
+			case 2:
+				row.invitee.RoleId = value
+			case 3:
+				row.invitee.Group = value
+			}
+		}
+		if row.invitee.Email == "" || row.invitee.RoleId == "" {
+			row.valid = false
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("the CSV file has no rows")
+	}
+
+	return rows, nil
+}
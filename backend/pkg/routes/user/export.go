@@ -0,0 +1,192 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/export"
+	"alphalabz/pkg/pocketbase"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Request a Personal Data Export
+// Starts building a ZIP archive of everything stored about a user: profile, settings, the lab books they
+// created or reviewed with their files and attachments, review comments and share records, described by
+// a manifest.json. The archive is built in the background, poll the export and download it once ready.
+// Users with the view:"*" permission on the "users" resource can export the data of another user.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Query Parameter:
+//   - `user_id` (string, optional) → The user to export, defaults to the caller.
+//
+// ✅ Successful Response (202 Accepted):
+//
+//	{
+//	    "id": "4f1c0e...",
+//	    "user_id": "user123",
+//	    "requested_by": "user123",
+//	    "status": "pending",
+//	    "created": "2025-01-30T17:23:01Z"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to export the data of another user.
+//   - 404 Not Found → User does not exist.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleRequestExport(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, ex *export.Exporter) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userId := r.URL.Query().Get("user_id")
+	if userId == "" {
+		userId = principal.UserId
+	}
+
+	if userId != principal.UserId {
+		_, starPermission, err := principal.Authorize(ce, "users", "view", "*")
+		if err != nil {
+			http.Error(w, "Failed to verify permission", http.StatusInternalServerError)
+			return
+		}
+		if !starPermission {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if _, ok := loadUserForLifecycle(w, userId, pbClient); !ok {
+			return
+		}
+	}
+
+	job, err := ex.Start(userId, principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to start export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// Get a Personal Data Export
+// Returns the status of an export, "pending", "ready" or "failed".
+// Exports can be downloaded for 24 hours once they are ready.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token, of the user who requested the export or whose data it holds.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the export.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "id": "4f1c0e...",
+//	    "user_id": "user123",
+//	    "requested_by": "user123",
+//	    "status": "ready",
+//	    "created": "2025-01-30T17:23:01Z",
+//	    "finished": "2025-01-30T17:23:09Z"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 404 Not Found → Unknown or expired export.
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+func HandleExportStatus(w http.ResponseWriter, r *http.Request, exportId string, ex *export.Exporter) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, ok := loadExportJob(w, r, exportId, ex)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// Download a Personal Data Export
+// Returns the ZIP archive of a ready export.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token, of the user who requested the export or whose data it holds.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the export.
+//
+// ✅ Successful Response (200 OK):
+// The archive, `Content-Type: application/zip`.
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 404 Not Found → Unknown or expired export.
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+//   - 409 Conflict → The export is still pending or failed.
+//   - 500 Internal Server Error → Server issue.
+func HandleDownloadExport(w http.ResponseWriter, r *http.Request, exportId string, ex *export.Exporter) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, ok := loadExportJob(w, r, exportId, ex)
+	if !ok {
+		return
+	}
+
+	if job.Status != export.JobReady {
+		http.Error(w, fmt.Sprintf("Export is %s", job.Status), http.StatusConflict)
+		return
+	}
+
+	archive, err := ex.Open(job)
+	if err != nil {
+		http.Error(w, "Failed to open export", http.StatusInternalServerError)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="alphalabz-export-%s.zip"`, job.UserId))
+	http.ServeContent(w, r, "", *job.Finished, archive)
+}
+
+// loadExportJob returns an export the caller requested or that holds their data, writing 404 Not Found otherwise.
+func loadExportJob(w http.ResponseWriter, r *http.Request, exportId string, ex *export.Exporter) (export.Job, bool) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return export.Job{}, false
+	}
+
+	job, found := ex.Job(exportId)
+	if !found || (job.UserId != principal.UserId && job.RequestedBy != principal.UserId) {
+		http.Error(w, "Export not found", http.StatusNotFound)
+		return export.Job{}, false
+	}
+
+	return job, true
+}
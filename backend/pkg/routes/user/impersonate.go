@@ -0,0 +1,167 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"net/http"
+)
+
+// Impersonate a User
+// Only users with the impersonate:"*" permission on the "users" resource can impersonate users.
+// Returns a short-lived token (15 minutes) to see the application as the user does, for troubleshooting.
+// Requests made with it are read-only, carry the `X-Impersonated-By` header and are recorded in the audit trail.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token (not a personal access token).
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the user to impersonate.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "status": "impersonating",
+//	    "token": "impersonation-token",
+//	    "user": { "id": "user123", "email": "student@univ.edu", "name": "Jane Doe" },
+//	    "expires": "2025-01-30 17:38:01.000Z",
+//	    "timestamp": "2025-01-30 17:23:01"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → The user tried to impersonate themselves.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → Missing permission, personal access token or impersonation token used, or the user is an admin (or has a role inheriting from it) or not active.
+//   - 404 Not Found → User does not exist.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleStartImpersonation(w http.ResponseWriter, r *http.Request, userId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() || principal.IsImpersonated() {
+		http.Error(w, "Impersonation requires a login token", http.StatusForbidden)
+		return
+	}
+
+	if userId == principal.UserId {
+		http.Error(w, "Cannot impersonate yourself", http.StatusBadRequest)
+		return
+	}
+
+	target, ok := loadUserForLifecycle(w, userId, pbClient)
+	if !ok {
+		return
+	}
+
+	if ce.IsAdminRole(target.RoleId) {
+		http.Error(w, "Cannot impersonate admin user", http.StatusForbidden)
+		return
+	}
+	if !target.IsActive() {
+		http.Error(w, "Cannot impersonate inactive user", http.StatusForbidden)
+		return
+	}
+
+	token, err := pbClient.ImpersonateUser(target.Id, int(auth.ImpersonationDuration.Seconds()))
+	if err != nil {
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	session, err := sr.RegisterImpersonation(token, r, principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	auth.Audit(pbClient, pocketbase.AuditLog{
+		ActorId: principal.UserId,
+		UserId:  target.Id,
+		Action:  auth.AuditImpersonationStart,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Status:  http.StatusOK,
+		IP:      tools.ClientIP(r),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "impersonating",
+		"token":  token,
+		"user": map[string]string{
+			"id":    target.Id,
+			"email": target.Email,
+			"name":  target.Name,
+		},
+		"expires":   session.Expires,
+		"timestamp": tools.Timestamp(),
+	})
+}
+
+// Stop Impersonating
+// Ends the impersonation session used for the request, its token stops working.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with the impersonation token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Impersonation ended"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → The token is not an impersonation token.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleStopImpersonation(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !principal.IsImpersonated() {
+		http.Error(w, "Not an impersonation token", http.StatusBadRequest)
+		return
+	}
+
+	if err := sr.EndToken(principal.Token); err != nil {
+		http.Error(w, "Failed to end impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	auth.Audit(pbClient, pocketbase.AuditLog{
+		ActorId: principal.ImpersonatorId,
+		UserId:  principal.UserId,
+		Action:  auth.AuditImpersonationStop,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Status:  http.StatusOK,
+		IP:      tools.ClientIP(r),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Impersonation ended"})
+}
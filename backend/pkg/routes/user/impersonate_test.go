@@ -0,0 +1,75 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// newUsersStub returns a client for a PocketBase stub that only serves the given user records.
+func newUsersStub(t *testing.T, users ...pocketbase.User) *pocketbase.PocketBaseClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := strings.CutPrefix(r.URL.Path, "/api/collections/users/records/")
+		if !ok || r.Method != http.MethodGet {
+			t.Errorf("unexpected PocketBase request: %s %s", r.Method, r.URL)
+			http.Error(w, "unexpected request", http.StatusInternalServerError)
+			return
+		}
+		for _, user := range users {
+			if user.Id == id {
+				json.NewEncoder(w).Encode(user)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return &pocketbase.PocketBaseClient{
+		BaseURL:       server.URL,
+		HTTPClient:    server.Client(),
+		UserInfoCache: cache.New(time.Minute, time.Minute),
+		TokenCache:    cache.New(time.Minute, time.Minute),
+	}
+}
+
+// newAdminInheritanceEnforcer returns an enforcer where "deputy" inherits from the admin role.
+func newAdminInheritanceEnforcer(t *testing.T) *casbin.CasbinEnforcer {
+	t.Helper()
+
+	ce, err := casbin.InitializeCasbin(nil, [][]interface{}{{"deputy", casbin.AdminRoleId}})
+	if err != nil {
+		t.Fatalf("InitializeCasbin() error = %v", err)
+	}
+	return ce
+}
+
+func TestImpersonationRefusesAdmins(t *testing.T) {
+	ce := newAdminInheritanceEnforcer(t)
+	pbClient := newUsersStub(t,
+		pocketbase.User{Id: "admin000000001", RoleId: casbin.AdminRoleId, Status: pocketbase.UserStatusActive},
+		pocketbase.User{Id: "deputy00000001", RoleId: "deputy", Status: pocketbase.UserStatusActive},
+	)
+	principal := &auth.Principal{UserId: "admin000000002", RoleId: casbin.AdminRoleId}
+
+	for _, userId := range []string{"admin000000001", "deputy00000001"} {
+		req := httptest.NewRequest(http.MethodPost, "/user/impersonate/"+userId, nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+		rec := httptest.NewRecorder()
+		HandleStartImpersonation(rec, req, userId, pbClient, ce, nil)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("impersonating %s status = %d, want 403", userId, rec.Code)
+		}
+	}
+}
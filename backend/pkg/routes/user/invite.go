@@ -1,26 +1,60 @@
 package user
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
 	"alphalabz/pkg/settings"
 	"alphalabz/pkg/smtp"
 	"alphalabz/pkg/tools"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
 	"time"
+)
+
+const (
+	invitePurpose = "invite"
+	inviteTTL     = 24 * time.Hour
+)
 
-	"github.com/golang-jwt/jwt/v5"
+var (
+	errInvalidEmail   = errors.New("invalid email address")
+	errRoleNotFound   = errors.New("role not found")
+	errForbiddenRole  = errors.New("unauthorized to create this role")
+	errAlreadyInvited = errors.New("email already registered or invited")
 )
 
 type Invitee struct {
 	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+	Group  string `json:"group,omitempty"`
 	RoleId string `json:"role_id"`
 }
 
+type invitationResponse struct {
+	Id         string `json:"id"`
+	Email      string `json:"email"`
+	Name       string `json:"name,omitempty"`
+	Group      string `json:"group,omitempty"`
+	RoleId     string `json:"role_id"`
+	InviterId  string `json:"inviter"`
+	Status     string `json:"status"`
+	Expires    string `json:"expires"`
+	LastSent   string `json:"last_sent"`
+	Created    string `json:"created,omitempty"`
+	InviteLink string `json:"invite_link,omitempty"`
+	EmailSent  *bool  `json:"email_sent,omitempty"`
+}
+
 // Invite a New User.
 // Only users with the appropriate permissions can invite new users.
+// The invitation is recorded, mailed to the invitee and can only be used once.
 //
 // ✅ Authorization:
 // - Requires an `Authorization` header with a valid token.
@@ -30,21 +64,31 @@ type Invitee struct {
 //
 //	{
 //	    "email": "test@example.com",
-//	    "role_id": "0003" // Allowed values depend on available roles, excluding "0001"
+//	    "name": "Jane Doe", // Optional, used in the invitation email
+//	    "group": "BIO-101", // Optional course or group
+//	    "role_id": "0003" // Allowed values depend on available roles, excluding "0001" and roles inheriting from it
 //	}
 //
 // ✅ Successful Response (200 OK):
 //
 //	{
-//	    "link": "https://example.com/invite?token=generated-invite-token"
+//	    "id": "invitation123",
+//	    "email": "test@example.com",
+//	    "role_id": "0003",
+//	    "inviter": "user123",
+//	    "status": "pending",
+//	    "expires": "2025-01-31 17:23:01.000Z",
+//	    "last_sent": "2025-01-30 17:23:01.000Z",
+//	    "invite_link": "https://example.com/invite?token=generated-invite-token",
+//	    "email_sent": true
 //	}
 //
 // ❌ Error Responses:
-//   - 400 Bad Request → Invalid JSON or missing fields
+//   - 400 Bad Request → Invalid JSON, missing fields, invalid email or unknown role
 //   - 401 Unauthorized → Missing or invalid Authorization token
 //   - 403 Forbidden → User is not authorized to create this role
-//   - 404 Not Found → Role does not exist
 //   - 405 Method Not Allowed → Request method is not POST
+//   - 409 Conflict → The email is already registered or has a pending invitation
 //   - 500 Internal Server Error → Server issue or failure in generating invite link
 func HandleInviteNewUser(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sc *smtp.SMTPClient) {
 	// Constrain request method
@@ -53,10 +97,9 @@ func HandleInviteNewUser(w http.ResponseWriter, r *http.Request, pbClient *pocke
 		return
 	}
 
-	// Extract token from header and validate it
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -68,7 +111,7 @@ func HandleInviteNewUser(w http.ResponseWriter, r *http.Request, pbClient *pocke
 	}
 
 	if inviteData.Email == "" || inviteData.RoleId == "" {
-		http.Error(w, "Email and name are required", http.StatusBadRequest)
+		http.Error(w, "Email and role_id are required", http.StatusBadRequest)
 		return
 	}
 
@@ -79,104 +122,394 @@ func HandleInviteNewUser(w http.ResponseWriter, r *http.Request, pbClient *pocke
 		return
 	}
 
-	// Check if role exists
-	roleExists := false
-	for _, role := range roles {
-		if role.Id == inviteData.RoleId {
-			roleExists = true
-			break
-		}
+	// Grant user scopes
+	scopes, err := principal.FetchScopes(ce, pbClient, "users", "create")
+	if err != nil {
+		http.Error(w, "Failed to fetch user scopes", http.StatusInternalServerError)
+		return
 	}
-	if !roleExists {
-		http.Error(w, "Role not found", http.StatusBadRequest)
+
+	invitation, inviteLink, emailSent, err := inviteUser(pbClient, ce, sc, principal.UserId, inviteData, roles, scopes)
+	switch {
+	case errors.Is(err, errInvalidEmail), errors.Is(err, errRoleNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errForbiddenRole):
+		http.Error(w, "Unauthorized to create this role", http.StatusForbidden)
+		return
+	case errors.Is(err, errAlreadyInvited):
+		http.Error(w, "Email already registered or invited", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
 		return
 	}
 
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	response := newInvitationResponse(invitation)
+	response.InviteLink = inviteLink
+	response.EmailSent = &emailSent
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// List Invitations
+// Lists the invitations sent by the caller. Users allowed to create users of every role ('*' scope) see all invitations.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token and the create permission on the "users" resource.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ Query Parameter:
+//   - `status` (string, optional) → One of "pending", "accepted", "revoked" or "expired".
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "items": [ { "id": "invitation123", "email": "test@example.com", "status": "pending", ... } ]
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid status filter.
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to invite users.
+//   - 405 Method Not Allowed → Request method is not GET.
+//   - 500 Internal Server Error → Server issue.
+func HandleListInvitations(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Grant user scopes
-	scopes, err := ce.ScopeFetcher(pbClient, userId, casbin.PermissionConfig{
-		Resources: "users",
-		Actions:   "create",
-	})
-	if err != nil {
-		http.Error(w, "Failed to fetch user scopes", http.StatusInternalServerError)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Check if user is authorized to create this role
-	if inviteData.RoleId == "0001" {
-		http.Error(w, "Unauthorized to create this role", http.StatusForbidden)
+	scopes, err := principal.FetchScopes(ce, pbClient, "users", "create")
+	if err != nil || len(scopes) == 0 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
-	} else {
-		var inviteeData = Invitee{
-			RoleId: inviteData.RoleId,
-			Email:  inviteData.Email,
-		}
-		if tools.Contains(scopes, "*") {
-			// Allow user to create all other roles
-			sendInviteResponse(w, inviteeData)
-			return
-		}
+	}
 
-		if tools.Contains(scopes, inviteData.RoleId) {
-			// Allow user to create this role
-			sendInviteResponse(w, inviteeData)
-			return
-		}
+	status := r.URL.Query().Get("status")
+	if status != "" && status != pocketbase.InvitationPending && status != pocketbase.InvitationAccepted &&
+		status != pocketbase.InvitationRevoked && status != "expired" {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
 
-		if !tools.Contains(scopes, inviteData.RoleId) && !tools.Contains(scopes, "*") {
-			http.Error(w, "Unauthorized to create this role", http.StatusForbidden)
-			return
-		}
+	filter := ""
+	if !tools.Contains(scopes, "*") {
+		filter = fmt.Sprintf("inviter='%s'", pocketbase.EscapeFilterValue(principal.UserId))
+	}
+
+	invitations, err := pbClient.ListInvitations(filter)
+	if err != nil {
+		http.Error(w, "Failed to list invitations", http.StatusInternalServerError)
+		return
+	}
 
+	items := []invitationResponse{}
+	for _, invitation := range invitations {
+		item := newInvitationResponse(invitation)
+		if status == "" || item.Status == status {
+			items = append(items, item)
+		}
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
 }
 
-func sendInviteResponse(w http.ResponseWriter, invitee Invitee) {
-	inviteLink, err := generateInvitation(invitee)
+// Resend an Invitation
+// Mails a new invite link and extends the expiry of a pending (or expired) invitation.
+// Only the inviter, or users allowed to create users of every role ('*' scope), can resend it.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token and the create permission on the "users" resource.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the invitation.
+//
+// ✅ Successful Response (200 OK):
+// Returns the invitation, like the invite endpoint.
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to manage this invitation.
+//   - 404 Not Found → Invitation does not exist.
+//   - 405 Method Not Allowed → Request method is not POST.
+//   - 409 Conflict → The invitation was already accepted or revoked.
+//   - 500 Internal Server Error → Server issue.
+func HandleResendInvitation(w http.ResponseWriter, r *http.Request, invitationId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sc *smtp.SMTPClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	invitation, ok := loadManagedInvitation(w, r, invitationId, pbClient, ce)
+	if !ok {
+		return
+	}
+
+	invitation.Expires = time.Now().UTC().Add(inviteTTL).Format(pocketbase.DateLayout)
+	if err := pbClient.UpdateInvitation(invitation.Id, pocketbase.Invitation{Expires: invitation.Expires}); err != nil {
+		http.Error(w, "Failed to update invitation", http.StatusInternalServerError)
+		return
+	}
+
+	inviteLink, emailSent, err := mailInvitation(pbClient, sc, &invitation)
 	if err != nil {
 		http.Error(w, "Failed to generate invitation link", http.StatusInternalServerError)
 		return
 	}
 
+	response := newInvitationResponse(invitation)
+	response.InviteLink = inviteLink
+	response.EmailSent = &emailSent
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Revoke an Invitation
+// The invite link stops working immediately.
+// Only the inviter, or users allowed to create users of every role ('*' scope), can revoke it.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token and the create permission on the "users" resource.
+//
+// ✅ HTTP Method: `DELETE`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the invitation.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Invitation revoked successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or invalid Authorization token.
+//   - 403 Forbidden → User is not allowed to manage this invitation.
+//   - 404 Not Found → Invitation does not exist.
+//   - 405 Method Not Allowed → Request method is not DELETE.
+//   - 409 Conflict → The invitation was already accepted or revoked.
+//   - 500 Internal Server Error → Server issue.
+func HandleRevokeInvitation(w http.ResponseWriter, r *http.Request, invitationId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	invitation, ok := loadManagedInvitation(w, r, invitationId, pbClient, ce)
+	if !ok {
+		return
+	}
+
+	if err := pbClient.UpdateInvitation(invitation.Id, pocketbase.Invitation{Status: pocketbase.InvitationRevoked}); err != nil {
+		http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"invite_link": inviteLink})
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invitation revoked successfully"})
 }
 
-func generateInvitation(invitee Invitee) (inviteLink string, err error) {
+// loadManagedInvitation loads a pending invitation the caller may resend or revoke, writing the error response otherwise.
+func loadManagedInvitation(w http.ResponseWriter, r *http.Request, invitationId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) (pocketbase.Invitation, bool) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return pocketbase.Invitation{}, false
+	}
+
+	scopes, err := principal.FetchScopes(ce, pbClient, "users", "create")
+	if err != nil || len(scopes) == 0 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return pocketbase.Invitation{}, false
+	}
+
+	invitation, err := pbClient.ViewInvitation(invitationId)
+	if err != nil {
+		http.Error(w, "Failed to load invitation", http.StatusInternalServerError)
+		return pocketbase.Invitation{}, false
+	}
+	if invitation.Id == "" {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return pocketbase.Invitation{}, false
+	}
+
+	if invitation.InviterId != principal.UserId && !tools.Contains(scopes, "*") {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return pocketbase.Invitation{}, false
+	}
+
+	if invitation.Status != pocketbase.InvitationPending {
+		http.Error(w, "Invitation is already "+invitation.Status, http.StatusConflict)
+		return pocketbase.Invitation{}, false
+	}
+
+	return invitation, true
+}
+
+// inviteUser checks an invitee against the available roles and the inviter's users:create scopes,
+// records the invitation and mails it.
+//
+// emailSent is false when the invitation was recorded but the mail could not be delivered, it can be resent later.
+func inviteUser(pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sc *smtp.SMTPClient, inviterId string, invitee Invitee, roles []pocketbase.Role, scopes []string) (invitation pocketbase.Invitation, inviteLink string, emailSent bool, err error) {
+	email := strings.ToLower(strings.TrimSpace(invitee.Email))
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return invitation, "", false, errInvalidEmail
+	}
+
+	if err := checkGrantableRole(ce, invitee.RoleId, roles, scopes); err != nil {
+		return invitation, "", false, err
+	}
+
+	existingUser, err := pbClient.FindUserByEmail(email)
+	if err != nil {
+		return invitation, "", false, err
+	}
+	if existingUser.Id != "" {
+		return invitation, "", false, errAlreadyInvited
+	}
+
+	now := time.Now().UTC()
+	pending, err := pbClient.ListInvitations(fmt.Sprintf("email='%s' && status='%s' && expires>'%s'",
+		pocketbase.EscapeFilterValue(email), pocketbase.InvitationPending, now.Format(pocketbase.DateLayout)))
+	if err != nil {
+		return invitation, "", false, err
+	}
+	if len(pending) > 0 {
+		return invitation, "", false, errAlreadyInvited
+	}
+
+	invitation = pocketbase.Invitation{
+		InviterId: inviterId,
+		Email:     email,
+		Name:      strings.TrimSpace(invitee.Name),
+		Group:     strings.TrimSpace(invitee.Group),
+		RoleId:    invitee.RoleId,
+		Status:    pocketbase.InvitationPending,
+		Expires:   now.Add(inviteTTL).Format(pocketbase.DateLayout),
+	}
+	if err := pbClient.CreateInvitation(&invitation); err != nil {
+		return invitation, "", false, err
+	}
+
+	inviteLink, emailSent, err = mailInvitation(pbClient, sc, &invitation)
+	return invitation, inviteLink, emailSent, err
+}
+
+// checkGrantableRole checks that a role exists and that the caller's users:create scopes allow granting it.
+// The admin role "0001", and any role inheriting from it, can never be granted this way.
+func checkGrantableRole(ce *casbin.CasbinEnforcer, roleId string, roles []pocketbase.Role, scopes []string) error {
+	// Check if role exists
+	roleExists := false
+	for _, role := range roles {
+		if role.Id == roleId {
+			roleExists = true
+			break
+		}
+	}
+	if !roleExists {
+		return errRoleNotFound
+	}
+
+	// Check if user is authorized to create this role
+	if ce.IsAdminRole(roleId) || (!tools.Contains(scopes, "*") && !tools.Contains(scopes, roleId)) {
+		return errForbiddenRole
+	}
+
+	return nil
+}
+
+// mailInvitation signs a new invite link for the invitation and mails it to the invitee.
+func mailInvitation(pbClient *pocketbase.PocketBaseClient, sc *smtp.SMTPClient, invitation *pocketbase.Invitation) (inviteLink string, emailSent bool, err error) {
+	inviteLink, err = generateInvitation(*invitation)
+	if err != nil {
+		return "", false, err
+	}
+
+	inviterName := "An administrator"
+	if inviter, err := pbClient.ViewUser(invitation.InviterId); err == nil && inviter.Name != "" {
+		inviterName = inviter.Name
+	}
+
+	body, err := smtp.RenderTemplate("invitation.html", map[string]string{
+		"Name":      invitation.Name,
+		"Inviter":   inviterName,
+		"Link":      inviteLink,
+		"ExpiresIn": "24 hours",
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	if _, err := sc.SendMail("You are invited to AlphaLabz", body, invitation.Email); err != nil {
+		log.Printf("Failed to send invitation %s: %v", invitation.Id, err)
+		return inviteLink, false, nil
+	}
+
+	invitation.LastSent = time.Now().UTC().Format(pocketbase.DateLayout)
+	if err := pbClient.UpdateInvitation(invitation.Id, pocketbase.Invitation{LastSent: invitation.LastSent}); err != nil {
+		log.Printf("Failed to record invitation %s as sent: %v", invitation.Id, err)
+	}
+
+	return inviteLink, true, nil
+}
+
+func generateInvitation(invitation pocketbase.Invitation) (inviteLink string, err error) {
 	// Get JWT secret from settings
 	settings, err := settings.LoadSettings("settings.yml")
 	if err != nil {
 		return "", fmt.Errorf("failed to load settings")
-	} else {
-		// Get invite JWT secret from settings
-		inviteSecret := settings.JWTSecret
-		if inviteSecret == "" {
-			return "", fmt.Errorf("invite secret not set")
-		}
+	}
 
-		// Generate JWT token
-		secretKey := []byte(inviteSecret)
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256,
-			jwt.MapClaims{
-				"email":   invitee.Email,
-				"role_id": invitee.RoleId,
-				"exp":     time.Now().Add(time.Hour * 24).Unix(),
-			})
-
-		tokenString, err := token.SignedString(secretKey)
-		if err != nil {
-			return "", err
-		}
+	expires, err := time.Parse(pocketbase.DateLayout, invitation.Expires)
+	if err != nil {
+		return "", fmt.Errorf("invalid invitation expiry: %w", err)
+	}
+
+	// The invitation id ties the token to its record, so it can only be used once
+	tokenString, err := tools.SignActionToken(settings.JWTSecret, invitePurpose, map[string]interface{}{
+		"email":         invitation.Email,
+		"role_id":       invitation.RoleId,
+		"invitation_id": invitation.Id,
+	}, time.Until(expires))
+	if err != nil {
+		return "", err
+	}
+
+	// Format the invite link
+	inviteLink = fmt.Sprintf("%s/invite?token=%s", settings.AppUrl, url.QueryEscape(tokenString))
+	return inviteLink, nil
+}
+
+// newInvitationResponse reports pending invitations past their expiry as "expired".
+func newInvitationResponse(invitation pocketbase.Invitation) invitationResponse {
+	status := invitation.Status
+	if expires, err := time.Parse(pocketbase.DateLayout, invitation.Expires); status == pocketbase.InvitationPending && err == nil && time.Now().After(expires) {
+		status = "expired"
+	}
 
-		// Format the invite link
-		inviteLink = fmt.Sprintf("%s/invite?token=%s", settings.AppUrl, tokenString)
-		return inviteLink, nil
+	return invitationResponse{
+		Id:        invitation.Id,
+		Email:     invitation.Email,
+		Name:      invitation.Name,
+		Group:     invitation.Group,
+		RoleId:    invitation.RoleId,
+		InviterId: invitation.InviterId,
+		Status:    status,
+		Expires:   invitation.Expires,
+		LastSent:  invitation.LastSent,
+		Created:   invitation.Created,
 	}
 }
@@ -1,9 +1,9 @@
 package user
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"net/http"
 )
@@ -65,24 +65,14 @@ func HandleUserList(w http.ResponseWriter, r *http.Request, pbClient *pocketbase
 		return
 	}
 
-	// Extract the authorization token from the request header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Fetch user permissions based on the authorization token
-	scopes, err := ce.ScopeFetcher(pbClient, userId, casbin.PermissionConfig{
-		Resources: "users",
-		Actions:   "list",
-	})
+	// Fetch user permissions based on the caller's role
+	scopes, err := principal.FetchScopes(ce, pbClient, "users", "list")
 	if err != nil {
 		http.Error(w, "Failed to fetch user permissions", http.StatusInternalServerError)
 		return
@@ -0,0 +1,355 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/mfa"
+	"alphalabz/pkg/pocketbase"
+	"encoding/json"
+	"net/http"
+)
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Start TOTP Enrollment
+// Only users with the update:"own" permission on the "users" resource can enroll an authenticator.
+// Returns a new TOTP secret and its provisioning URI (to be shown as a QR code).
+// MFA is only turned on after a code is confirmed through `/user/account/mfa/verify`.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "secret": "BASE32SECRET",
+//	    "uri": "otpauth://totp/AlphaLabz:user@example.com?..."
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 409 Conflict → MFA is already enabled.
+//   - 500 Internal Server Error → Server issue.
+func HandleMFAEnroll(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot manage two-factor authentication", http.StatusForbidden)
+		return
+	}
+
+	record, err := pbClient.GetUserMFA(principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to load MFA settings", http.StatusInternalServerError)
+		return
+	}
+	if record.Enabled {
+		http.Error(w, "MFA is already enabled", http.StatusConflict)
+		return
+	}
+
+	userInfo, err := pbClient.ViewUser(principal.UserId)
+	if err != nil {
+		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		http.Error(w, "Failed to generate secret", http.StatusInternalServerError)
+		return
+	}
+
+	record.Secret = secret
+	if err := pbClient.SaveUserMFA(&record); err != nil {
+		http.Error(w, "Failed to save MFA settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret": secret,
+		"uri":    mfa.ProvisioningURI(mfa.Issuer, userInfo.Email, secret),
+	})
+}
+
+// Confirm TOTP Enrollment
+// Only users with the update:"own" permission on the "users" resource can enroll an authenticator.
+// Turns MFA on once a code from the authenticator is confirmed, and returns the recovery codes once.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "code": "123456"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "MFA enabled successfully",
+//	    "recovery_codes": ["abcde-12345", ...]
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, invalid code or no enrollment started.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 409 Conflict → MFA is already enabled.
+//   - 500 Internal Server Error → Server issue.
+func HandleMFAVerify(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot manage two-factor authentication", http.StatusForbidden)
+		return
+	}
+
+	var request mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	record, err := pbClient.GetUserMFA(principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to load MFA settings", http.StatusInternalServerError)
+		return
+	}
+	if record.Enabled {
+		http.Error(w, "MFA is already enabled", http.StatusConflict)
+		return
+	}
+	if record.Secret == "" {
+		http.Error(w, "No MFA enrollment started", http.StatusBadRequest)
+		return
+	}
+
+	if !mfa.ValidateUnusedCode(principal.UserId, record.Secret, request.Code) {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, hashes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	record.Enabled = true
+	record.RecoveryCodes = hashes
+	if err := pbClient.SaveUserMFA(&record); err != nil {
+		http.Error(w, "Failed to save MFA settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "MFA enabled successfully",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// Regenerate Recovery Codes
+// Only users with the update:"own" permission on the "users" resource can regenerate their recovery codes.
+// Requires a valid TOTP or recovery code, all previous recovery codes stop working.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "code": "123456"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "recovery_codes": ["abcde-12345", ...]
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON or MFA is not enabled.
+//   - 401 Unauthorized → Missing or Invalid Authorization token, or invalid code.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleMFARecoveryCodes(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot manage two-factor authentication", http.StatusForbidden)
+		return
+	}
+
+	var request mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	record, err := pbClient.GetUserMFA(principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to load MFA settings", http.StatusInternalServerError)
+		return
+	}
+	if !record.Enabled {
+		http.Error(w, "MFA is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	valid, err := mfa.Verify(pbClient, &record, request.Code)
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	recoveryCodes, hashes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	record.RecoveryCodes = hashes
+	if err := pbClient.SaveUserMFA(&record); err != nil {
+		http.Error(w, "Failed to save MFA settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"recovery_codes": recoveryCodes})
+}
+
+// Disable MFA
+// Only users with the update:"own" permission on the "users" resource can turn their second factor off.
+// Requires a valid TOTP or recovery code, and is refused when the user's role enforces MFA.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `DELETE`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "code": "123456"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "MFA disabled successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON or MFA is not enabled.
+//   - 401 Unauthorized → Missing or Invalid Authorization token, or invalid code.
+//   - 403 Forbidden → User does not have the required permissions, or the role enforces MFA, or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only DELETE is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleMFADisable(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot manage two-factor authentication", http.StatusForbidden)
+		return
+	}
+
+	var request mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	required, err := mfa.Required(pbClient, principal.RoleId)
+	if err != nil {
+		http.Error(w, "Failed to load role", http.StatusInternalServerError)
+		return
+	}
+	if required {
+		http.Error(w, "MFA is required for your role", http.StatusForbidden)
+		return
+	}
+
+	record, err := pbClient.GetUserMFA(principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to load MFA settings", http.StatusInternalServerError)
+		return
+	}
+	if !record.Enabled {
+		http.Error(w, "MFA is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	valid, err := mfa.Verify(pbClient, &record, request.Code)
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if err := pbClient.DeleteUserMFA(record.Id); err != nil {
+		http.Error(w, "Failed to disable MFA", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "MFA disabled successfully"})
+}
@@ -0,0 +1,292 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/passkey"
+	"alphalabz/pkg/pocketbase"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type passkeyRegisterRequest struct {
+	Ceremony   string          `json:"ceremony"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+type passkeyResponse struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Created string `json:"created"`
+}
+
+// Start Passkey Registration
+// Only users with the update:"own" permission on the "users" resource can register passkeys.
+// Returns the WebAuthn options to pass to `navigator.credentials.create()`.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "ceremony": "ceremony-id",
+//	    "options": { "publicKey": { "challenge": "...", "rp": { ... }, "user": { ... } } }
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue.
+//   - 503 Service Unavailable → Passkeys are not configured.
+func HandlePasskeyRegisterBegin(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, service *passkey.Service) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if service == nil {
+		http.Error(w, "Passkeys are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot register passkeys", http.StatusForbidden)
+		return
+	}
+
+	userInfo, err := pbClient.ViewUser(principal.UserId)
+	if err != nil {
+		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
+		return
+	}
+
+	displayName := userInfo.Name
+	if displayName == "" {
+		displayName = userInfo.Email
+	}
+
+	ceremony, options, err := service.BeginRegistration(principal.UserId, userInfo.Email, displayName)
+	if err != nil {
+		http.Error(w, "Failed to start passkey registration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ceremony": ceremony,
+		"options":  options,
+	})
+}
+
+// Complete Passkey Registration
+// Only users with the update:"own" permission on the "users" resource can register passkeys.
+// Verifies the credential returned by `navigator.credentials.create()` and stores it on the user.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "ceremony": "ceremony-id",
+//	    "name": "Lab terminal 3", (optional)
+//	    "credential": { "id": "...", "rawId": "...", "type": "public-key", "response": { ... } }
+//	}
+//
+// ✅ Successful Response (201 Created):
+//
+//	{
+//	    "id": "credential-id",
+//	    "name": "Lab terminal 3",
+//	    "created": "2025-01-30T17:23:01Z"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, unknown ceremony or the credential could not be verified.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 503 Service Unavailable → Passkeys are not configured.
+func HandlePasskeyRegisterFinish(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, service *passkey.Service) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if service == nil {
+		http.Error(w, "Passkeys are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot register passkeys", http.StatusForbidden)
+		return
+	}
+
+	var registerRequest passkeyRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&registerRequest); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	credential, err := service.FinishRegistration(registerRequest.Ceremony, principal.UserId, registerRequest.Name, bytes.NewReader(registerRequest.Credential))
+	if errors.Is(err, passkey.ErrUnknownCeremony) {
+		http.Error(w, "Unknown or expired registration request", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Println("Passkey registration failed:", err)
+		http.Error(w, "Failed to register passkey", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(passkeyResponse{
+		Id:      credential.Id(),
+		Name:    credential.Name,
+		Created: credential.Created,
+	})
+}
+
+// List Passkeys
+// Only users with the view:"own" permission on the "users" resource can list their passkeys.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "items": [
+//	        {
+//	            "id": "credential-id",
+//	            "name": "Lab terminal 3",
+//	            "created": "2025-01-30T17:23:01Z"
+//	        }
+//	    ]
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions.
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+//   - 500 Internal Server Error → Server issue.
+//   - 503 Service Unavailable → Passkeys are not configured.
+func HandleListPasskeys(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, service *passkey.Service) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if service == nil {
+		http.Error(w, "Passkeys are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	credentials, err := service.List(principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to list passkeys", http.StatusInternalServerError)
+		return
+	}
+
+	items := []passkeyResponse{}
+	for _, credential := range credentials {
+		items = append(items, passkeyResponse{
+			Id:      credential.Id(),
+			Name:    credential.Name,
+			Created: credential.Created,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// Remove a Passkey
+// Only users with the update:"own" permission on the "users" resource can remove their passkeys.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `DELETE`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the passkey to remove.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Passkey removed successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 404 Not Found → The user has no passkey with this ID.
+//   - 405 Method Not Allowed → Invalid HTTP method (only DELETE is allowed).
+//   - 500 Internal Server Error → Server issue.
+//   - 503 Service Unavailable → Passkeys are not configured.
+func HandleRemovePasskey(w http.ResponseWriter, r *http.Request, credentialId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, service *passkey.Service) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if service == nil {
+		http.Error(w, "Passkeys are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot remove passkeys", http.StatusForbidden)
+		return
+	}
+
+	err := service.Remove(principal.UserId, credentialId)
+	if errors.Is(err, passkey.ErrCredentialUnknown) {
+		http.Error(w, "Passkey not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to remove passkey", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Passkey removed successfully"})
+}
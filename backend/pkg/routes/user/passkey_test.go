@@ -0,0 +1,200 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/passkey"
+	"alphalabz/pkg/passkey/passkeytest"
+	"alphalabz/pkg/pocketbase"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	testRPId   = "labs.univ.edu"
+	testOrigin = "https://labs.univ.edu"
+	testUserId = "user0000000001"
+)
+
+// newPasskeyTest returns a passkey service on an in-memory store, and a PocketBase stub serving the test user.
+func newPasskeyTest(t *testing.T) (*passkey.Service, *pocketbase.PocketBaseClient) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/collections/users/records/"+testUserId {
+			t.Errorf("unexpected PocketBase request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(pocketbase.User{Id: testUserId, Email: "ada@univ.edu", Name: "Ada Lovelace"})
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := passkey.NewService(testRPId, "AlphaLabz", []string{testOrigin}, passkeytest.NewStore())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	return service, &pocketbase.PocketBaseClient{
+		BaseURL:       server.URL,
+		HTTPClient:    server.Client(),
+		UserInfoCache: cache.New(time.Minute, time.Minute),
+		TokenCache:    cache.New(time.Minute, time.Minute),
+	}
+}
+
+// servePasskey calls a passkey handler as the given principal and decodes the JSON response into out.
+func servePasskey(t *testing.T, principal *auth.Principal, method string, body interface{}, handler func(w http.ResponseWriter, r *http.Request), out interface{}) int {
+	t.Helper()
+
+	var encoded []byte
+	if body != nil {
+		encoded, _ = json.Marshal(body)
+	}
+
+	req := httptest.NewRequest(method, "/user/account/passkeys", bytes.NewReader(encoded))
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if out != nil && rec.Code < 300 {
+		if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+			t.Fatalf("invalid JSON response: %v", err)
+		}
+	}
+	return rec.Code
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	service, pbClient := newPasskeyTest(t)
+	principal := &auth.Principal{UserId: testUserId}
+	authenticator, err := passkeytest.NewAuthenticator(testRPId, testOrigin)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	registerBegin := func(w http.ResponseWriter, r *http.Request) {
+		HandlePasskeyRegisterBegin(w, r, pbClient, nil, service)
+	}
+	registerFinish := func(w http.ResponseWriter, r *http.Request) {
+		HandlePasskeyRegisterFinish(w, r, pbClient, nil, service)
+	}
+	list := func(w http.ResponseWriter, r *http.Request) {
+		HandleListPasskeys(w, r, pbClient, nil, service)
+	}
+
+	var begin struct {
+		Ceremony string                      `json:"ceremony"`
+		Options  protocol.CredentialCreation `json:"options"`
+	}
+	if code := servePasskey(t, principal, http.MethodPost, nil, registerBegin, &begin); code != http.StatusOK {
+		t.Fatalf("register begin status = %d", code)
+	}
+	if begin.Options.Response.User.Name != "ada@univ.edu" || begin.Options.Response.RelyingParty.ID != testRPId {
+		t.Fatalf("registration options = %+v", begin.Options.Response)
+	}
+
+	credential, err := authenticator.Register(begin.Options.Response)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// The credential is only accepted along with the ceremony it answers
+	unknown := map[string]interface{}{"ceremony": "unknown", "credential": json.RawMessage(credential)}
+	if code := servePasskey(t, principal, http.MethodPost, unknown, registerFinish, nil); code != http.StatusBadRequest {
+		t.Fatalf("register finish with an unknown ceremony status = %d, want 400", code)
+	}
+
+	var created passkeyResponse
+	finish := map[string]interface{}{"ceremony": begin.Ceremony, "name": "Lab terminal 3", "credential": json.RawMessage(credential)}
+	if code := servePasskey(t, principal, http.MethodPost, finish, registerFinish, &created); code != http.StatusCreated {
+		t.Fatalf("register finish status = %d, want 201", code)
+	}
+	if created.Id != authenticator.CredentialId() || created.Name != "Lab terminal 3" {
+		t.Fatalf("register finish = %+v", created)
+	}
+
+	var listed struct {
+		Items []passkeyResponse `json:"items"`
+	}
+	if code := servePasskey(t, principal, http.MethodGet, nil, list, &listed); code != http.StatusOK {
+		t.Fatalf("list status = %d", code)
+	}
+	if len(listed.Items) != 1 || listed.Items[0].Id != created.Id {
+		t.Fatalf("listed passkeys = %+v", listed.Items)
+	}
+
+	// The registered passkey logs the user in
+	ceremony, options, err := service.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	assertion, err := authenticator.Assert(options.Response)
+	if err != nil {
+		t.Fatalf("Assert() error = %v", err)
+	}
+	if userId, err := service.FinishLogin(ceremony, bytes.NewReader(assertion)); err != nil || userId != testUserId {
+		t.Fatalf("FinishLogin() = %q, %v", userId, err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"unknown passkey", "unknown", http.StatusNotFound},
+		{"registered passkey", created.Id, http.StatusOK},
+		{"removed passkey", created.Id, http.StatusNotFound},
+	} {
+		remove := func(w http.ResponseWriter, r *http.Request) {
+			HandleRemovePasskey(w, r, tt.id, pbClient, nil, service)
+		}
+		if code := servePasskey(t, principal, http.MethodDelete, nil, remove, nil); code != tt.want {
+			t.Errorf("remove %s status = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}
+
+func TestPasskeyRegistrationRefused(t *testing.T) {
+	service, pbClient := newPasskeyTest(t)
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		service   *passkey.Service
+		want      int
+	}{
+		{"personal access token", &auth.Principal{UserId: testUserId, APITokenId: "token0000000001"}, service, http.StatusForbidden},
+		{"passkeys not configured", &auth.Principal{UserId: testUserId}, nil, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := map[string]func(w http.ResponseWriter, r *http.Request){
+				"begin": func(w http.ResponseWriter, r *http.Request) {
+					HandlePasskeyRegisterBegin(w, r, pbClient, nil, tt.service)
+				},
+				"finish": func(w http.ResponseWriter, r *http.Request) {
+					HandlePasskeyRegisterFinish(w, r, pbClient, nil, tt.service)
+				},
+			}
+			for step, handler := range handlers {
+				body := map[string]string{"ceremony": "unknown"}
+				if code := servePasskey(t, tt.principal, http.MethodPost, body, handler, nil); code != tt.want {
+					t.Errorf("register %s status = %d, want %d", step, code, tt.want)
+				}
+			}
+		})
+	}
+
+	// Nothing was registered along the way
+	if credentials, _ := service.List(testUserId); len(credentials) != 0 {
+		t.Errorf("%d passkeys registered, want 0", len(credentials))
+	}
+}
@@ -0,0 +1,139 @@
+package user
+
+import (
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/settings"
+	"alphalabz/pkg/smtp"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+type registrationRequest struct {
+	Email           string `json:"email"`
+	Name            string `json:"name"`
+	Password        string `json:"password"`
+	PasswordConfirm string `json:"passwordConfirm"`
+}
+
+// Register without an Invitation
+// When open signup is enabled in settings, anyone with an email address of an allowed domain can register.
+// The account stays pending, and cannot sign in, until an admin approves it from the approval queue.
+// A verification link is mailed right away so the address is confirmed once the account is approved.
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "email": "student@univ.edu",
+//	    "name": "Jane Doe",
+//	    "password": "securepassword",
+//	    "passwordConfirm": "securepassword"
+//	}
+//
+// ✅ Successful Response (202 Accepted):
+//
+//	{
+//	    "message": "Registration received, an administrator will review it",
+//	    "status": "pending"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, missing fields, invalid email, passwords do not match or password refused by the policy.
+//   - 403 Forbidden → Open signup is disabled, or the email domain is not allowed.
+//   - 405 Method Not Allowed → Request method is not POST.
+//   - 409 Conflict → The email is already registered.
+//   - 500 Internal Server Error → Server issue.
+func HandleRegister(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, sc *smtp.SMTPClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+	if !settings.Signup.Enabled || settings.Signup.DefaultRoleId == "" || settings.Signup.DefaultRoleId == "0001" {
+		http.Error(w, "Open signup is disabled", http.StatusForbidden)
+		return
+	}
+
+	var registration registrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(registration.Email))
+	if email == "" || registration.Password == "" || registration.PasswordConfirm == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	if registration.Password != registration.PasswordConfirm {
+		http.Error(w, "Passwords do not match", http.StatusBadRequest)
+		return
+	}
+
+	if !allowedSignupDomain(email, settings.Signup.AllowedDomains) {
+		http.Error(w, "Registration is not open for this email domain", http.StatusForbidden)
+		return
+	}
+
+	if rejectWeakPassword(w, registration.Password, email, registration.Name) {
+		return
+	}
+
+	existingUser, err := pbClient.FindUserByEmail(email)
+	if err != nil {
+		http.Error(w, "Failed to check email", http.StatusInternalServerError)
+		return
+	}
+	if existingUser.Id != "" {
+		http.Error(w, "Email already registered", http.StatusConflict)
+		return
+	}
+
+	name := strings.TrimSpace(registration.Name)
+	userId, err := pbClient.NewPendingUser(email, registration.Password, registration.PasswordConfirm, name, settings.Signup.DefaultRoleId)
+	if err != nil {
+		log.Println("Failed to register user:", err)
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	if err := sendVerificationEmail(sc, pocketbase.User{Id: userId, Email: email, Name: name}); err != nil {
+		log.Println("Failed to send verification email:", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Registration received, an administrator will review it",
+		"status":  pocketbase.UserStatusPending,
+	})
+}
+
+// allowedSignupDomain reports whether the domain of the email is one of the allowed domains (case-insensitive).
+// Subdomains are not included, list them explicitly.
+func allowedSignupDomain(email string, allowedDomains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+
+	for _, allowed := range allowedDomains {
+		if strings.EqualFold(domain, strings.TrimPrefix(strings.TrimSpace(allowed), "@")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,16 +1,19 @@
 package user
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 )
 
 // Remove a User
 // Only users with the delete:"*" permission on the "users" resource can remove a user from the system.
+// The lab books created or reviewed by the user, including pending reviews, are handed over to another user first
+// (lab books the heir would both create and review are left without reviewer), and the user's settings are deleted with them. Suspend users instead to keep their history.
 //
 // ✅ Authorization:
 // Requires an `Authorization` header with a valid token.
@@ -19,53 +22,47 @@ import (
 //
 // ✅ Query Parameter:
 //   - `id` (string, required) → The ID of the user to be deleted.
+//   - `reassign_to` (string, required) → The ID of the active user receiving the lab books and pending reviews.
 //
 // ✅ Successful Response (200 OK):
 //
 //	{
-//	    "message": "User deleted successfully"
+//	    "message": "User deleted successfully",
+//	    "reassigned_lab_books": 4
 //	}
 //
 // ❌ Error Responses:
-//   - 400 Bad Request → Missing required User ID parameter.
+//   - 400 Bad Request → Missing User ID or reassign_to parameter, or reassign_to is the deleted user or not active.
 //   - 401 Unauthorized → Missing or invalid Authorization token.
-//   - 403 Forbidden → User does not have the required permissions or attempted to delete an admin user.
+//   - 403 Forbidden → User does not have the required permissions or attempted to delete an admin user (or a user whose role inherits from it).
 //   - 404 Not Found → User does not exist.
 //   - 405 Method Not Allowed → Invalid HTTP method (only DELETE is allowed).
 //   - 500 Internal Server Error → Server issue or failure deleting the user.
-func HandleUserRemove(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+func HandleUserRemove(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sr *auth.SessionRegistry) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Extract token from header and validate it
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	// Get delete user Id from request url
+	userId := r.URL.Query().Get("id")
+	if userId == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
 		return
 	}
 
-	hasPermmission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "users",
-		Actions:   "delete",
-		Scopes:    "*",
-	})
-	if err != nil || !hasPermmission {
-		fmt.Println(hasPermmission)
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	reassignTo := r.URL.Query().Get("reassign_to")
+	if reassignTo == "" {
+		http.Error(w, "reassign_to is required", http.StatusBadRequest)
 		return
 	}
-
-	// Get delete user Id from request url
-	userId := r.URL.Query().Get("id")
-	if userId == "" {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+	if reassignTo == userId {
+		http.Error(w, "Cannot reassign lab books to the deleted user", http.StatusBadRequest)
 		return
 	}
 
 	// list all users before deleting the user to ensure it exists
-	users, totalCount, err := pbClient.ListUsers([]string{"id", "role", "name"}, nil, fmt.Sprintf("(id='%s')", userId))
+	users, totalCount, err := pbClient.ListUsers([]string{"id", "role", "name", "user_settings"}, nil, fmt.Sprintf("(id='%s')", pocketbase.EscapeFilterValue(userId)))
 	if err != nil {
 		fmt.Println(err)
 		http.Error(w, "Failed to list users", http.StatusInternalServerError)
@@ -79,16 +76,57 @@ func HandleUserRemove(w http.ResponseWriter, r *http.Request, pbClient *pocketba
 	}
 
 	// Check if the user is an admin before deleting it. If yes, return error.
-	if users[0].RoleId == "0001" {
+	if ce.IsAdminRole(users[0].RoleId) {
 		http.Error(w, "Cannot delete admin user", http.StatusForbidden)
 		return
 	}
 
+	// The lab books need an active owner
+	exists, err := pbClient.CheckUserExists(reassignTo)
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "reassign_to user not found", http.StatusBadRequest)
+		return
+	}
+	heir, err := pbClient.ViewUser(reassignTo)
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return
+	}
+	if !heir.IsActive() {
+		http.Error(w, "reassign_to user is not active", http.StatusBadRequest)
+		return
+	}
+
+	reassigned, err := pbClient.ReassignLabbooks(userId, reassignTo)
+	if err != nil {
+		log.Println("Failed to reassign lab books:", err)
+		http.Error(w, "Failed to reassign lab books", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := sr.EndAll(userId); err != nil {
+		log.Printf("Failed to end the sessions of user %s: %v", userId, err)
+	}
+
 	if err := pbClient.DeleteUser(userId); err != nil {
 		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
 
+	if settingsId := users[0].SettingId; settingsId != "" {
+		if err := pbClient.DeleteUserSettings(settingsId); err != nil {
+			log.Printf("Failed to delete settings %s of user %s: %v", settingsId, userId, err)
+		}
+	}
+
 	// Response
-	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":              "User deleted successfully",
+		"reassigned_lab_books": reassigned,
+	})
 }
@@ -0,0 +1,156 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type roleChangeRequest struct {
+	RoleId string `json:"role_id"`
+}
+
+// Change the Role of a User
+// The caller can only move users between roles of their update permission scopes on the "users" resource,
+// the user's current role included. Only admins can grant or take away the admin role ("0001") or a role inheriting from it.
+// The change is recorded in the audit trail.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `PATCH`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the user.
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "role_id": "0002" // Allowed values depend on the caller's scopes
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Role updated successfully",
+//	    "previous_role_id": "0003",
+//	    "role_id": "0002"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, missing or unknown role, or the user tried to change their own role.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User is not authorized to change the role of this user to this role.
+//   - 404 Not Found → User does not exist.
+//   - 405 Method Not Allowed → Invalid HTTP method (only PATCH is allowed).
+//   - 409 Conflict → The user already has this role.
+//   - 500 Internal Server Error → Server issue.
+func HandleChangeUserRole(w http.ResponseWriter, r *http.Request, userId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var change roleChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil || change.RoleId == "" {
+		http.Error(w, "role_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if userId == principal.UserId {
+		http.Error(w, "Cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	scopes, err := principal.FetchScopes(ce, pbClient, "users", "update")
+	if err != nil {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	roles, err := pbClient.ListRoles([]string{"id", "name", "type"}, "")
+	if err != nil {
+		http.Error(w, "Failed to get available roles", http.StatusInternalServerError)
+		return
+	}
+
+	target, ok := loadUserForLifecycle(w, userId, pbClient)
+	if !ok {
+		return
+	}
+
+	switch err := checkRoleChange(ce, principal.RoleId, target.RoleId, change.RoleId, roles, scopes); {
+	case errors.Is(err, errRoleNotFound):
+		http.Error(w, "Role not found", http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Unauthorized to grant this role", http.StatusForbidden)
+		return
+	}
+
+	if target.RoleId == change.RoleId {
+		http.Error(w, "User already has this role", http.StatusConflict)
+		return
+	}
+
+	// UpdateProfile drops the cached user, so the new role applies from the next request
+	if err := pbClient.UpdateProfile(target.Id, pocketbase.User{RoleId: change.RoleId}); err != nil {
+		http.Error(w, "Failed to update role", http.StatusInternalServerError)
+		return
+	}
+
+	auth.Audit(pbClient, pocketbase.AuditLog{
+		ActorId: principal.UserId,
+		UserId:  target.Id,
+		Action:  auth.AuditRoleChange,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Status:  http.StatusOK,
+		IP:      tools.ClientIP(r),
+		Details: fmt.Sprintf("%s -> %s", target.RoleId, change.RoleId),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":          "Role updated successfully",
+		"previous_role_id": target.RoleId,
+		"role_id":          change.RoleId,
+	})
+}
+
+// checkRoleChange checks that a caller with the given users:update scopes can move a user from one role to another.
+// The admin role ("0001"), and any role inheriting from it, can only be granted or taken away by admins.
+func checkRoleChange(ce *casbin.CasbinEnforcer, callerRoleId, fromRoleId, toRoleId string, roles []pocketbase.Role, scopes []string) error {
+	roleExists := false
+	for _, role := range roles {
+		if role.Id == toRoleId {
+			roleExists = true
+			break
+		}
+	}
+	if !roleExists {
+		return errRoleNotFound
+	}
+
+	for _, roleId := range []string{fromRoleId, toRoleId} {
+		if ce.IsAdminRole(roleId) && !ce.IsAdminRole(callerRoleId) {
+			return errForbiddenRole
+		}
+		if !tools.Contains(scopes, "*") && !tools.Contains(scopes, roleId) {
+			return errForbiddenRole
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package user
+
+import (
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"errors"
+	"testing"
+)
+
+func TestCheckRoleChange(t *testing.T) {
+	// "deputy" inherits from the admin role, so granting it makes an admin
+	ce, err := casbin.InitializeCasbin(nil, [][]interface{}{{"deputy", casbin.AdminRoleId}})
+	if err != nil {
+		t.Fatalf("InitializeCasbin() error = %v", err)
+	}
+	roles := []pocketbase.Role{{Id: casbin.AdminRoleId}, {Id: "deputy"}, {Id: "0002"}, {Id: "0003"}}
+
+	tests := []struct {
+		name   string
+		caller string
+		from   string
+		to     string
+		scopes []string
+		want   error
+	}{
+		{"any role", "0002", "0003", "0002", []string{"*"}, nil},
+		{"role in scopes", "0002", "0003", "0002", []string{"0002", "0003"}, nil},
+		{"current role out of scopes", "0002", "0003", "0002", []string{"0002"}, errForbiddenRole},
+		{"new role out of scopes", "0002", "0003", "0002", []string{"0003"}, errForbiddenRole},
+		{"unknown role", "0002", "0003", "9999", []string{"*"}, errRoleNotFound},
+		{"admin role by non-admin", "0002", "0003", casbin.AdminRoleId, []string{"*"}, errForbiddenRole},
+		{"role inheriting admin by non-admin", "0002", "0003", "deputy", []string{"*"}, errForbiddenRole},
+		{"role inheriting admin taken away by non-admin", "0002", "deputy", "0003", []string{"*"}, errForbiddenRole},
+		{"role inheriting admin by admin", casbin.AdminRoleId, "0003", "deputy", []string{"*"}, nil},
+		{"admin role by role inheriting admin", "deputy", "0003", casbin.AdminRoleId, []string{"*"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkRoleChange(ce, tt.caller, tt.from, tt.to, roles, tt.scopes); !errors.Is(err, tt.want) {
+				t.Errorf("checkRoleChange() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckGrantableRole(t *testing.T) {
+	ce := newAdminInheritanceEnforcer(t)
+	roles := []pocketbase.Role{{Id: casbin.AdminRoleId}, {Id: "deputy"}, {Id: "0003"}}
+
+	tests := []struct {
+		name   string
+		roleId string
+		scopes []string
+		want   error
+	}{
+		{"any role", "0003", []string{"*"}, nil},
+		{"role in scopes", "0003", []string{"0003"}, nil},
+		{"role out of scopes", "0003", []string{"0002"}, errForbiddenRole},
+		{"unknown role", "9999", []string{"*"}, errRoleNotFound},
+		{"admin role", casbin.AdminRoleId, []string{"*"}, errForbiddenRole},
+		{"role inheriting admin", "deputy", []string{"*"}, errForbiddenRole},
+		{"role inheriting admin in scopes", "deputy", []string{"deputy"}, errForbiddenRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkGrantableRole(ce, tt.roleId, roles, tt.scopes); !errors.Is(err, tt.want) {
+				t.Errorf("checkGrantableRole() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"net/http"
+)
+
+type sessionResponse struct {
+	Id        string `json:"id"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	Issued    string `json:"issued"`
+	Expires   string `json:"expires"`
+	Current   bool   `json:"current"`
+	// ImpersonatedBy is the admin who impersonated the user in this session.
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
+}
+
+// List Active Sessions
+// Only users with the view:"own" permission on the "users" resource can list their sessions.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "items": [
+//	        {
+//	            "id": "session123",
+//	            "ip": "203.0.113.7",
+//	            "user_agent": "Mozilla/5.0 ...",
+//	            "issued": "2025-01-30 17:23:01.000Z",
+//	            "expires": "2025-02-06 17:23:01.000Z",
+//	            "current": true,
+//	            "impersonated_by": "admin123" // Only for sessions of an admin impersonating the user
+//	        }
+//	    ]
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions.
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleListSessions(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := sr.List(principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	currentHash := tools.HashToken(principal.Token)
+	items := []sessionResponse{}
+	for _, session := range sessions {
+		items = append(items, sessionResponse{
+			Id:        session.Id,
+			IP:        session.IP,
+			UserAgent: session.UserAgent,
+			Issued:    session.Created,
+			Expires:   session.Expires,
+			Current:   session.TokenHash == currentHash,
+
+			ImpersonatedBy: session.ImpersonatorId,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// End a Session
+// Only users with the update:"own" permission on the "users" resource can end their sessions.
+// The token of the session is refused from now on.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `DELETE`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the session to end.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Session ended successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Missing session ID.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions, or the request was made with a personal access token.
+//   - 404 Not Found → No session with this ID belongs to the user.
+//   - 405 Method Not Allowed → Invalid HTTP method (only DELETE is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleEndSession(w http.ResponseWriter, r *http.Request, sessionId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot end sessions", http.StatusForbidden)
+		return
+	}
+
+	if sessionId == "" {
+		http.Error(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+
+	sessions, err := sr.List(principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	for _, session := range sessions {
+		if session.Id != sessionId {
+			continue
+		}
+
+		if err := sr.End(session); err != nil {
+			http.Error(w, "Failed to end session", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Session ended successfully"})
+		return
+	}
+
+	http.Error(w, "Session not found", http.StatusNotFound)
+}
+
+// Force Logout a User
+// Only users with the update:"*" permission on the "users" resource can end the sessions of other users.
+// Every login token of the user is refused from now on, personal access tokens are not affected.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `DELETE`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the user to log out.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Sessions ended successfully",
+//	    "ended": 3
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Missing user ID.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions.
+//   - 405 Method Not Allowed → Invalid HTTP method (only DELETE is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleEndUserSessions(w http.ResponseWriter, r *http.Request, userId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if userId == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ended, err := sr.EndAll(userId)
+	if err != nil {
+		http.Error(w, "Failed to end sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Sessions ended successfully",
+		"ended":   ended,
+	})
+}
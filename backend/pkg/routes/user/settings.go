@@ -1,12 +1,11 @@
 package user
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/csv"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"os"
 )
@@ -43,21 +42,9 @@ func HandlUpdateSettings(w http.ResponseWriter, r *http.Request, pbClient *pocke
 		return
 	}
 
-	// Extract token from header and validate it
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	hasPermmission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "users",
-		Actions:   "update",
-		Scopes:    "own",
-	})
-	if err != nil || !hasPermmission {
-		fmt.Println(hasPermmission)
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -93,21 +80,8 @@ func HandlUpdateSettings(w http.ResponseWriter, r *http.Request, pbClient *pocke
 		return
 	}
 
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Error retrieving user ID", http.StatusInternalServerError)
-		return
-	}
-
-	// Get user's settings Id
-	userInfo, err := pbClient.ViewUser(userId)
-	if err != nil {
-		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
-		return
-	}
-
 	// Update user settings in the database
-	if err = pbClient.UpdateSettings(userInfo.SettingId, map[string]interface{}{
+	if err = pbClient.UpdateSettings(principal.SettingId, map[string]interface{}{
 		"language": updateSettings.AppLanguage,
 		"theme":    updateSettings.Theme}); err != nil {
 		http.Error(w, "Error updating user settings", http.StatusInternalServerError)
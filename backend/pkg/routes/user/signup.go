@@ -8,11 +8,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // Sign Up a New User
@@ -24,7 +24,7 @@ import (
 // - `Content-Type: multipart/form-data`
 //
 // - Fields:
-//   - `token` (string, required) → JWT token for authentication.
+//   - `token` (string, required) → Invite token from the invitation email, it can only be used once.
 //   - `username` (string, required) → The desired username.
 //   - `password` (string, required) → The password for the account.
 //   - `passwordConfirm` (string, required) → Must match `password`.
@@ -39,7 +39,7 @@ import (
 //	}
 //
 // ❌ Error Responses:
-//   - 400 Bad Request → Missing required fields, invalid password confirmation, or incorrect format.
+//   - 400 Bad Request → Missing required fields, invalid password confirmation, password refused by the policy, or incorrect format.
 //   - 401 Unauthorized → Missing or Invalid Authorization token.
 //   - 415 Unsupported Media Type → Avatar file format is not allowed.
 //   - 500 Internal Server Error → Server issue or file saving error.
@@ -77,13 +77,6 @@ func HandleSignUp(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.P
 		return
 	}
 
-	// Parse JWT token
-	roleId, email, err := parseJWT(token)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusBadRequest)
-		return
-	}
-
 	// Make sure the password and passwordConfirm match
 	if password != passwordConfirm {
 		http.Error(w, "Passwords do not match", http.StatusBadRequest)
@@ -105,6 +98,18 @@ func HandleSignUp(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.P
 		return
 	}
 
+	// Parse JWT token, claiming the invitation
+	roleId, email, invitationId, err := parseJWT(pbClient, token)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusBadRequest)
+		return
+	}
+
+	if rejectWeakPassword(w, password, email, username) {
+		releaseInvitation(pbClient, invitationId)
+		return
+	}
+
 	// Check if the user has uploaded an avatar and validate it
 	var allowedMimeTypes = map[string]bool{
 		"image/jpeg":    true,
@@ -123,6 +128,7 @@ func HandleSignUp(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.P
 	if err == http.ErrMissingFile {
 		filePath = ""
 	} else if err != nil {
+		releaseInvitation(pbClient, invitationId)
 		http.Error(w, "Failed to upload avatar", http.StatusBadRequest)
 		return
 	} else {
@@ -135,6 +141,7 @@ func HandleSignUp(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.P
 		// Save the file to disk
 		dst, err := os.Create(filePath)
 		if err != nil {
+			releaseInvitation(pbClient, invitationId)
 			http.Error(w, "Failed to save avatar", http.StatusInternalServerError)
 			return
 		}
@@ -142,6 +149,7 @@ func HandleSignUp(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.P
 
 		_, err = io.Copy(dst, file)
 		if err != nil {
+			releaseInvitation(pbClient, invitationId)
 			http.Error(w, "Error saving file", http.StatusInternalServerError)
 			return
 		}
@@ -149,6 +157,7 @@ func HandleSignUp(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.P
 		// Check the mime type of the uploaded file
 		savedFile, err := os.Open(filePath)
 		if err != nil {
+			releaseInvitation(pbClient, invitationId)
 			http.Error(w, "Failed to open saved avatar", http.StatusInternalServerError)
 			return
 		}
@@ -157,53 +166,88 @@ func HandleSignUp(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.P
 		mimeType, err := tools.CheckMimeType(savedFile)
 		if err != nil || !allowedMimeTypes[mimeType] {
 			os.Remove(filePath) // Delete the file if it's not an allowed mime type
+			releaseInvitation(pbClient, invitationId)
 			http.Error(w, "Invalid file format", http.StatusUnsupportedMediaType)
 			return
 		}
 	}
 
 	// Regist new user
-	if err = pbClient.NewUser(email, password, passwordConfirm, username, gender, dateOfBirth, roleId, filePath); err != nil {
+	userId, err := pbClient.NewUser(email, password, passwordConfirm, username, gender, dateOfBirth, roleId, filePath)
+	if err != nil {
 		fmt.Println(err)
+		releaseInvitation(pbClient, invitationId)
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
 
+	// The invite link was mailed to this address, so it is already verified
+	if err := pbClient.UpdateProfile(userId, pocketbase.User{Verified: true}); err != nil {
+		log.Printf("Failed to mark user %s as verified: %v", userId, err)
+	}
+
 	// Response
 	json.NewEncoder(w).Encode(map[string]string{"message": "User created successfully"})
 }
 
-func parseJWT(tokenString string) (roleId, email string, err error) {
-	claims := jwt.MapClaims{}
+// invitationClaims serializes the check-and-accept of invitations so an invite link can only be used once.
+var invitationClaims sync.Mutex
 
-	tokenParsed, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		// Validate the token signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		// Load secret key from settings
-		settingsData, err := settings.LoadSettings("settings.yml")
-		if err != nil {
-			return nil, fmt.Errorf("failed to load settings")
-		}
-
-		// Return secret key for verification
-		return []byte(settingsData.JWTSecret), nil
-	})
+// parseJWT verifies an invite token and claims its invitation by marking it accepted.
+//
+// The invitation must still be pending and unexpired, a second signup with the same link is rejected.
+// Call releaseInvitation if the user could not be created so the link can be used again.
+func parseJWT(pbClient *pocketbase.PocketBaseClient, tokenString string) (roleId, email, invitationId string, err error) {
+	// Load secret key from settings
+	settingsData, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to load settings")
+	}
 
-	if err != nil || !tokenParsed.Valid {
-		return "", "", fmt.Errorf("invalid token")
+	claims, err := tools.ParseActionToken(settingsData.JWTSecret, invitePurpose, tokenString)
+	if err != nil {
+		return "", "", "", err
 	}
 
 	// Extract claims with type assertion
 	var ok bool
 	if email, ok = claims["email"].(string); !ok {
-		return "", "", fmt.Errorf("invalid token: missing email claim")
+		return "", "", "", fmt.Errorf("invalid token: missing email claim")
 	}
 	if roleId, ok = claims["role_id"].(string); !ok {
-		return "", "", fmt.Errorf("invalid token: missing role_id claim")
+		return "", "", "", fmt.Errorf("invalid token: missing role_id claim")
+	}
+	if invitationId, ok = claims["invitation_id"].(string); !ok || invitationId == "" {
+		return "", "", "", fmt.Errorf("invalid token: missing invitation_id claim")
 	}
 
-	return roleId, email, nil
+	invitationClaims.Lock()
+	defer invitationClaims.Unlock()
+
+	invitation, err := pbClient.ViewInvitation(invitationId)
+	if err != nil {
+		return "", "", "", err
+	}
+	if invitation.Id == "" || invitation.Status != pocketbase.InvitationPending ||
+		invitation.Email != email || invitation.RoleId != roleId {
+		return "", "", "", fmt.Errorf("invalid token: invitation is no longer valid")
+	}
+
+	expires, err := time.Parse(pocketbase.DateLayout, invitation.Expires)
+	if err != nil || time.Now().After(expires) {
+		return "", "", "", fmt.Errorf("invalid token: invitation expired")
+	}
+
+	if err := pbClient.UpdateInvitation(invitationId, pocketbase.Invitation{Status: pocketbase.InvitationAccepted}); err != nil {
+		return "", "", "", err
+	}
+
+	return roleId, email, invitationId, nil
+}
+
+// releaseInvitation puts a claimed invitation back to pending after a failed signup.
+func releaseInvitation(pbClient *pocketbase.PocketBaseClient, invitationId string) {
+	if err := pbClient.UpdateInvitation(invitationId, pocketbase.Invitation{Status: pocketbase.InvitationPending}); err != nil {
+		log.Printf("Failed to release invitation %s: %v", invitationId, err)
+	}
 }
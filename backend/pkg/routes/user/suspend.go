@@ -0,0 +1,156 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Suspend a User
+// Only users with the update:"*" permission on the "users" resource can suspend users.
+// A suspended user cannot sign in and all of their sessions end, but their lab books and history are kept.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the user to suspend.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "User suspended successfully",
+//	    "ended_sessions": 2
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → The user tried to suspend themselves.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions or attempted to suspend an admin user (or a user whose role inherits from it).
+//   - 404 Not Found → User does not exist.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 409 Conflict → The user is not active.
+//   - 500 Internal Server Error → Server issue.
+func HandleSuspendUser(w http.ResponseWriter, r *http.Request, userId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, sr *auth.SessionRegistry) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if userId == principal.UserId {
+		http.Error(w, "Cannot suspend yourself", http.StatusBadRequest)
+		return
+	}
+
+	target, ok := loadUserForLifecycle(w, userId, pbClient)
+	if !ok {
+		return
+	}
+
+	if ce.IsAdminRole(target.RoleId) {
+		http.Error(w, "Cannot suspend admin user", http.StatusForbidden)
+		return
+	}
+
+	if target.Status != "" && target.Status != pocketbase.UserStatusActive {
+		http.Error(w, "User is not active", http.StatusConflict)
+		return
+	}
+
+	if err := pbClient.UpdateProfile(target.Id, pocketbase.User{Status: pocketbase.UserStatusSuspended}); err != nil {
+		http.Error(w, "Failed to suspend user", http.StatusInternalServerError)
+		return
+	}
+
+	ended, err := sr.EndAll(target.Id)
+	if err != nil {
+		log.Printf("Failed to end the sessions of suspended user %s: %v", target.Id, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "User suspended successfully",
+		"ended_sessions": ended,
+	})
+}
+
+// Reactivate a User
+// Only users with the update:"*" permission on the "users" resource can reactivate suspended users.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the suspended user.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "User reactivated successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions.
+//   - 404 Not Found → User does not exist.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 409 Conflict → The user is not suspended.
+//   - 500 Internal Server Error → Server issue.
+func HandleReactivateUser(w http.ResponseWriter, r *http.Request, userId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target, ok := loadUserForLifecycle(w, userId, pbClient)
+	if !ok {
+		return
+	}
+
+	if target.Status != pocketbase.UserStatusSuspended {
+		http.Error(w, "User is not suspended", http.StatusConflict)
+		return
+	}
+
+	if err := pbClient.UpdateProfile(target.Id, pocketbase.User{Status: pocketbase.UserStatusActive}); err != nil {
+		http.Error(w, "Failed to reactivate user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User reactivated successfully"})
+}
+
+// loadUserForLifecycle loads a user by ID, writing 404 Not Found if it does not exist.
+func loadUserForLifecycle(w http.ResponseWriter, userId string, pbClient *pocketbase.PocketBaseClient) (pocketbase.User, bool) {
+	exists, err := pbClient.CheckUserExists(userId)
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return pocketbase.User{}, false
+	}
+	if !exists {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return pocketbase.User{}, false
+	}
+
+	user, err := pbClient.ViewUser(userId)
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return pocketbase.User{}, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,30 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSuspensionRefusesAdmins(t *testing.T) {
+	ce := newAdminInheritanceEnforcer(t)
+	pbClient := newUsersStub(t,
+		pocketbase.User{Id: "admin000000001", RoleId: casbin.AdminRoleId, Status: pocketbase.UserStatusActive},
+		pocketbase.User{Id: "deputy00000001", RoleId: "deputy", Status: pocketbase.UserStatusActive},
+	)
+	principal := &auth.Principal{UserId: "admin000000002", RoleId: casbin.AdminRoleId}
+
+	for _, userId := range []string{"admin000000001", "deputy00000001"} {
+		req := httptest.NewRequest(http.MethodPost, "/user/"+userId+"/suspend", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+		rec := httptest.NewRecorder()
+		HandleSuspendUser(rec, req, userId, pbClient, ce, nil)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("suspending %s status = %d, want 403", userId, rec.Code)
+		}
+	}
+}
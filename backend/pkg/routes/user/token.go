@@ -0,0 +1,263 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxAPITokenLifetime caps the optional expiry of a personal access token.
+const maxAPITokenLifetime = 3650
+
+type apiTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+// List Personal Access Tokens
+// Only users with the view:"own" permission on the "users" resource can list their tokens.
+// The token values are never returned, only their metadata.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `GET`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "items": [
+//	        {
+//	            "id": "token123",
+//	            "name": "Microscope PC",
+//	            "scopes": ["lab_books:create:own"],
+//	            "expires": "",
+//	            "created": "2025-01-30 17:23:01.000Z"
+//	        }
+//	    ]
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions.
+//   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleListAPITokens(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := pbClient.ListAPITokens(principal.UserId)
+	if err != nil {
+		http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range tokens {
+		tokens[i].TokenHash = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": tokens})
+}
+
+// Create a Personal Access Token
+// Only users with the update:"own" permission on the "users" resource can create tokens.
+// Scopes use the `resource:action:scope` vocabulary of the role permissions and must be granted by the user's role.
+// The token is shown once, only its hash is stored. Tokens cannot create other tokens.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid login token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "name": "Microscope PC",
+//	    "scopes": ["lab_books:create:own", "lab_books:view:own"],
+//	    "expiresInDays": 365 (optional, never expires when omitted)
+//	}
+//
+// ✅ Successful Response (201 Created):
+//
+//	{
+//	    "id": "token123",
+//	    "name": "Microscope PC",
+//	    "scopes": ["lab_books:create:own", "lab_books:view:own"],
+//	    "expires": "2026-01-30 17:23:01.000Z",
+//	    "token": "alz_..."
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, missing name or scopes, or invalid scope / expiry.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions, a scope is not granted by the role,
+//     or the request was made with a personal access token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleCreateAPIToken(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if principal.IsAPIToken() {
+		http.Error(w, "API tokens cannot create other tokens", http.StatusForbidden)
+		return
+	}
+
+	var tokenRequest apiTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&tokenRequest); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokenRequest.Name = strings.TrimSpace(tokenRequest.Name)
+	if tokenRequest.Name == "" || len(tokenRequest.Scopes) == 0 {
+		http.Error(w, "Name and scopes are required", http.StatusBadRequest)
+		return
+	}
+
+	if tokenRequest.ExpiresInDays < 0 || tokenRequest.ExpiresInDays > maxAPITokenLifetime {
+		http.Error(w, fmt.Sprintf("expiresInDays must be between 0 and %d", maxAPITokenLifetime), http.StatusBadRequest)
+		return
+	}
+
+	// A token can never do more than the role of its owner
+	for _, value := range tokenRequest.Scopes {
+		resource, action, scopes, err := auth.ParseScope(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, scope := range scopes {
+			hasPermission, _, err := ce.VerifyRolePermission(principal.RoleId, casbin.PermissionConfig{
+				Resources: resource,
+				Actions:   action,
+				Scopes:    scope,
+			})
+			if err != nil {
+				http.Error(w, "Failed to verify permission", http.StatusInternalServerError)
+				return
+			}
+			if !hasPermission {
+				http.Error(w, fmt.Sprintf("Scope %s:%s:%s is not granted by your role", resource, action, scope), http.StatusForbidden)
+				return
+			}
+		}
+	}
+
+	rawToken, err := auth.GenerateAPIToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	token := pocketbase.APIToken{
+		UserId:    principal.UserId,
+		Name:      tokenRequest.Name,
+		TokenHash: tools.HashToken(rawToken),
+		Scopes:    tokenRequest.Scopes,
+	}
+	if tokenRequest.ExpiresInDays > 0 {
+		token.Expires = time.Now().UTC().AddDate(0, 0, tokenRequest.ExpiresInDays).Format(pocketbase.DateLayout)
+	}
+
+	if err := pbClient.CreateAPIToken(&token); err != nil {
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      token.Id,
+		"name":    token.Name,
+		"scopes":  token.Scopes,
+		"expires": token.Expires,
+		"token":   rawToken,
+	})
+}
+
+// Revoke a Personal Access Token
+// Only users with the update:"own" permission on the "users" resource can revoke their tokens.
+// The token stops working immediately.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `DELETE`
+//
+// ✅ URL Parameter:
+//   - `id` (string, required) → The ID of the token to revoke.
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Token revoked successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Missing token ID.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions.
+//   - 404 Not Found → No token with this ID belongs to the user.
+//   - 405 Method Not Allowed → Invalid HTTP method (only DELETE is allowed).
+//   - 500 Internal Server Error → Server issue.
+func HandleRevokeAPIToken(w http.ResponseWriter, r *http.Request, tokenId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if tokenId == "" {
+		http.Error(w, "Token ID is required", http.StatusBadRequest)
+		return
+	}
+
+	token, err := pbClient.ViewAPIToken(tokenId)
+	if err != nil {
+		http.Error(w, "Failed to load token", http.StatusInternalServerError)
+		return
+	}
+	if token.Id == "" || token.UserId != principal.UserId {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	if err := pbClient.DeleteAPIToken(token.Id); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Token revoked successfully"})
+}
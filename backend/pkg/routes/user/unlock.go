@@ -0,0 +1,75 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/casbin"
+	"alphalabz/pkg/pocketbase"
+	"encoding/json"
+	"net"
+	"net/http"
+)
+
+type unlockRequest struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
+
+// Unlock a Login
+// Only users with the update:"*" permission on the "users" resource can unlock accounts.
+// Lifts the lockout and backoff of an account after failed logins, of an IP address, or both.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "email": "user@example.com", (optional)
+//	    "ip": "203.0.113.7" (optional)
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Unlocked successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON, neither email nor ip given, or invalid ip.
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 403 Forbidden → User does not have the required permissions.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+func HandleUnlockLogin(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer, lg *auth.LoginGuard) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var unlockData unlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&unlockData); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if unlockData.Email == "" && unlockData.IP == "" {
+		http.Error(w, "Email or ip is required", http.StatusBadRequest)
+		return
+	}
+
+	if unlockData.IP != "" && net.ParseIP(unlockData.IP) == nil {
+		http.Error(w, "Invalid ip address", http.StatusBadRequest)
+		return
+	}
+
+	if unlockData.Email != "" {
+		lg.Unlock(unlockData.Email)
+	}
+	if unlockData.IP != "" {
+		lg.UnlockIP(unlockData.IP)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Unlocked successfully"})
+}
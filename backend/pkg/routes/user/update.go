@@ -1,6 +1,7 @@
 package user
 
 import (
+	"alphalabz/pkg/auth"
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
 	"alphalabz/pkg/tools"
@@ -44,25 +45,9 @@ func HandlUpdateProfile(w http.ResponseWriter, r *http.Request, pbClient *pocket
 		return
 	}
 
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "users",
-		Actions:   "update",
-		Scopes:    "own",
-	})
-	if !hasPermission || err != nil {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -90,7 +75,7 @@ func HandlUpdateProfile(w http.ResponseWriter, r *http.Request, pbClient *pocket
 	}
 
 	// Update user account information in the database
-	if err := pbClient.UpdateProfile(userId, updateInfo); err != nil {
+	if err := pbClient.UpdateProfile(principal.UserId, updateInfo); err != nil {
 		http.Error(w, "Failed to update user account info", http.StatusInternalServerError)
 		return
 	}
@@ -136,27 +121,9 @@ func HandleUpdateAvatar(w http.ResponseWriter, r *http.Request, pbClient *pocket
 		return
 	}
 
-	// Extract the token from the request header
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Verify the user's permission to update their avatar
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "users",
-		Actions:   "update",
-		Scopes:    "own",
-	})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
-	userId, err := tools.GetUserIdFromJWT(rawToken)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -219,7 +186,7 @@ func HandleUpdateAvatar(w http.ResponseWriter, r *http.Request, pbClient *pocket
 	}
 
 	// Update the user's avatar in the database
-	if err := pbClient.UpdateAvatar(userId, filePath); err != nil {
+	if err := pbClient.UpdateAvatar(principal.UserId, filePath); err != nil {
 		http.Error(w, "Failed to update avatar", http.StatusInternalServerError)
 		return
 	} else {
@@ -0,0 +1,170 @@
+package user
+
+import (
+	"alphalabz/pkg/auth"
+	"alphalabz/pkg/pocketbase"
+	"alphalabz/pkg/settings"
+	"alphalabz/pkg/smtp"
+	"alphalabz/pkg/tools"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	emailVerificationPurpose = "email_verification"
+	emailVerificationTTL     = 24 * time.Hour
+)
+
+type emailVerificationConfirm struct {
+	Token string `json:"token"`
+}
+
+// Resend the Verification Email
+// Sends a new verification link to the email address of the current user.
+//
+// ✅ Authorization:
+// Requires an `Authorization` header with a valid token.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Verification email sent"
+//	}
+//
+// ❌ Error Responses:
+//   - 401 Unauthorized → Missing or Invalid Authorization token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 409 Conflict → The email address is already verified.
+//   - 500 Internal Server Error → Server issue or failure sending the email.
+func HandleResendVerification(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient, sc *smtp.SMTPClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userInfo, err := pbClient.ViewUser(principal.UserId)
+	if err != nil {
+		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
+		return
+	}
+	if userInfo.Verified {
+		http.Error(w, "Email address already verified", http.StatusConflict)
+		return
+	}
+
+	if err := sendVerificationEmail(sc, userInfo); err != nil {
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Verification email sent"})
+}
+
+// Confirm Email Verification
+// Marks the email address as verified using the token from the verification email.
+// The token stops working once the address is verified, or if it changed in the meantime.
+//
+// ✅ HTTP Method: `POST`
+//
+// ✅ Request Body (JSON):
+//
+//	{
+//	    "token": "verification-token"
+//	}
+//
+// ✅ Successful Response (200 OK):
+//
+//	{
+//	    "message": "Email verified successfully"
+//	}
+//
+// ❌ Error Responses:
+//   - 400 Bad Request → Invalid JSON or invalid / used token.
+//   - 405 Method Not Allowed → Invalid HTTP method (only POST is allowed).
+//   - 500 Internal Server Error → Server issue or failure updating the user.
+func HandleConfirmVerification(w http.ResponseWriter, r *http.Request, pbClient *pocketbase.PocketBaseClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var confirm emailVerificationConfirm
+	if err := json.NewDecoder(r.Body).Decode(&confirm); err != nil || confirm.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	claims, err := tools.ParseActionToken(settings.JWTSecret, emailVerificationPurpose, confirm.Token)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	userId, _ := claims["user_id"].(string)
+	email, _ := claims["email"].(string)
+	if userId == "" || email == "" {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	// The token is bound to the unverified address: it is used up once the address is verified
+	userInfo, err := pbClient.ViewUser(userId)
+	if err != nil || userInfo.Email != email || userInfo.Verified {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	if err := pbClient.UpdateProfile(userId, pocketbase.User{Verified: true}); err != nil {
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified successfully"})
+}
+
+// sendVerificationEmail mails a link confirming the user's current email address.
+func sendVerificationEmail(sc *smtp.SMTPClient, user pocketbase.User) error {
+	settings, err := settings.LoadSettings("settings.yml")
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	token, err := tools.SignActionToken(settings.JWTSecret, emailVerificationPurpose, map[string]interface{}{
+		"user_id": user.Id,
+		"email":   user.Email,
+	}, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	body, err := smtp.RenderTemplate("verify_email.html", map[string]string{
+		"Name":      user.Name,
+		"Link":      fmt.Sprintf("%s/verify-email?token=%s", settings.AppUrl, url.QueryEscape(token)),
+		"ExpiresIn": "24 hours",
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = sc.SendMail("Verify your AlphaLabz email address", body, user.Email)
+	return err
+}
@@ -3,13 +3,13 @@ package user
 import (
 	"alphalabz/pkg/casbin"
 	"alphalabz/pkg/pocketbase"
-	"alphalabz/pkg/tools"
 	"encoding/json"
 	"net/http"
 )
 
 // View User Profile
-// Only users with the view:"own" permission on the "users" resource can retrieve their profile information.
+// Users can retrieve their own profile with the view:"own" permission on the "users" resource,
+// and any profile with view:"*". The route checks the scopes against the user (auth.RequireRecordPermission).
 //
 // ✅ Authorization:
 // Requires an `Authorization` header with a valid token.
@@ -36,7 +36,8 @@ import (
 // ❌ Error Responses:
 //   - 400 Bad Request → Missing required User ID parameter.
 //   - 401 Unauthorized → Missing or invalid Authorization token.
-//   - 403 Forbidden → User does not have the required permissions.
+//   - 403 Forbidden → User is not allowed to view this profile.
+//   - 404 Not Found → User does not exist.
 //   - 405 Method Not Allowed → Invalid HTTP method (only GET is allowed).
 //   - 500 Internal Server Error → Server issue or failure retrieving user information.
 func HandleUserView(w http.ResponseWriter, r *http.Request, userId string, pbClient *pocketbase.PocketBaseClient, ce *casbin.CasbinEnforcer) {
@@ -50,23 +51,6 @@ func HandleUserView(w http.ResponseWriter, r *http.Request, userId string, pbCli
 		return
 	}
 
-	// Extract token from header and validate it
-	rawToken, err := tools.TokenExtractor(r.Header.Get("Authorization"))
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	hasPermission, _, err := ce.VerifyJWTPermission(pbClient, rawToken, casbin.PermissionConfig{
-		Resources: "users",
-		Actions:   "view",
-		Scopes:    "own",
-	})
-	if err != nil || !hasPermission {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
 	userInfo, err := pbClient.ViewUser(userId)
 	if err != nil {
 		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
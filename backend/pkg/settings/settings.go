@@ -30,6 +30,57 @@ type Settings struct {
 		FromAddress string `yaml:"from_address"`
 		FromName    string `yaml:"from_name"`
 	} `yaml:"Mailer"`
+	OIDC struct {
+		Enabled       bool     `yaml:"enabled"`
+		Issuer        string   `yaml:"issuer"`
+		ClientId      string   `yaml:"client_id"`
+		ClientSecret  string   `yaml:"client_secret"`
+		RedirectUrl   string   `yaml:"redirect_url"`
+		Scopes        []string `yaml:"scopes"`
+		AutoProvision bool     `yaml:"auto_provision"`
+		DefaultRoleId string   `yaml:"default_role_id"`
+		ClaimMapping  struct {
+			Subject string `yaml:"subject"` // Defaults to "sub"
+			Email   string `yaml:"email"`   // Defaults to "email"
+			Name    string `yaml:"name"`    // Defaults to "name"
+		} `yaml:"claim_mapping"`
+	} `yaml:"OIDC"`
+	LDAP struct {
+		Enabled            bool              `yaml:"enabled"`
+		Url                string            `yaml:"url"` // ldap://host:389 or ldaps://host:636
+		StartTLS           bool              `yaml:"start_tls"`
+		InsecureSkipVerify bool              `yaml:"insecure_skip_verify"`
+		BindDN             string            `yaml:"bind_dn"`
+		BindPassword       string            `yaml:"bind_password"`
+		BaseDN             string            `yaml:"base_dn"`
+		UserFilter         string            `yaml:"user_filter"` // %s is replaced by the login
+		EmailAttribute     string            `yaml:"email_attribute"`
+		NameAttribute      string            `yaml:"name_attribute"`
+		GroupAttribute     string            `yaml:"group_attribute"`
+		GroupRoles         map[string]string `yaml:"group_roles"` // Group DN -> role id
+		DefaultRoleId      string            `yaml:"default_role_id"`
+		FallbackToLocal    bool              `yaml:"fallback_to_local"`
+	} `yaml:"LDAP"`
+	WebAuthn struct {
+		Enabled       bool     `yaml:"enabled"`
+		RPID          string   `yaml:"rp_id"` // Domain of the app, e.g. alphalabz.net
+		RPDisplayName string   `yaml:"rp_display_name"`
+		RPOrigins     []string `yaml:"rp_origins"` // e.g. https://alphalabz.net
+	} `yaml:"WebAuthn"`
+	PasswordPolicy struct {
+		MinLength          int    `yaml:"min_length"` // Defaults to 8
+		RequireUppercase   bool   `yaml:"require_uppercase"`
+		RequireLowercase   bool   `yaml:"require_lowercase"`
+		RequireDigit       bool   `yaml:"require_digit"`
+		RequireSymbol      bool   `yaml:"require_symbol"`
+		ForbidPersonalInfo bool   `yaml:"forbid_personal_info"` // Reject passwords containing the email or name
+		BreachedHashesDir  string `yaml:"breached_hashes_dir"`  // SHA-1 range files named by hash prefix, e.g. 5BAA6.txt
+	} `yaml:"PasswordPolicy"`
+	Signup struct {
+		Enabled        bool     `yaml:"enabled"`
+		AllowedDomains []string `yaml:"allowed_domains"` // e.g. univ.edu, only these email domains can register
+		DefaultRoleId  string   `yaml:"default_role_id"` // Role held while pending, admins choose the role on approval
+	} `yaml:"Signup"`
 	AppUrl         string `yaml:"AppUrl"`
 	IsInitialized  bool   `yaml:"IsInitialized"`
 	JWTSecret      string `yaml:"JWTSecret"`
@@ -0,0 +1,21 @@
+package smtp
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+)
+
+//go:embed template/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "template/*.html"))
+
+// RenderTemplate renders one of the embedded mail templates (e.g. "password_reset.html") into an HTML body.
+func RenderTemplate(name string, data interface{}) (string, error) {
+	var body bytes.Buffer
+	if err := templates.ExecuteTemplate(&body, name, data); err != nil {
+		return "", err
+	}
+	return body.String(), nil
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SignActionToken signs a short-lived token meant for a single purpose (password reset, email change, ...).
+//
+// Nothing keeps track of the tokens: to make one single-use, bind it with a claim to the state it changes
+// (e.g. the user's credentials) and compare that claim when it is used.
+func SignActionToken(secret, purpose string, claims map[string]interface{}, ttl time.Duration) (string, error) {
+	if secret == "" {
+		return "", errors.New("token secret not set")
+	}
+
+	mapClaims := jwt.MapClaims{}
+	for key, value := range claims {
+		mapClaims[key] = value
+	}
+	mapClaims["purpose"] = purpose
+	mapClaims["exp"] = time.Now().Add(ttl).Unix()
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims).SignedString([]byte(secret))
+}
+
+// ParseActionToken verifies a token created by SignActionToken and checks it was issued for the given purpose.
+func ParseActionToken(secret, purpose, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if claimPurpose, _ := claims["purpose"].(string); claimPurpose != purpose {
+		return nil, errors.New("invalid token: wrong purpose")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,17 @@
+package tools
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the IP address of the peer that sent the request.
+//
+// Forwarding headers are ignored on purpose, they can be set freely by the client.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -17,33 +19,49 @@ type PocketBaseJWTPayload struct {
 	jwt.RegisteredClaims
 }
 
-// GetUserIdFromJWT extracts the user ID from a JWT token.
+// ParsePocketBaseJWT decodes the claims of a PocketBase JWT token WITHOUT verifying its signature.
 //
-// It returns an empty string and an error if the token is invalid or does not contain a user ID.
-func GetUserIdFromJWT(tokenString string) (string, error) {
+// The result must only be trusted after the token has been verified by PocketBase (see pocketbase.VerifyUserToken).
+func ParsePocketBaseJWT(tokenString string) (*PocketBaseJWTPayload, error) {
 	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &PocketBaseJWTPayload{})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*PocketBaseJWTPayload); ok {
-		return claims.Id, nil
+		return claims, nil
 	}
 
-	return "", errors.New("invalid token or claims")
+	return nil, errors.New("invalid token or claims")
+}
+
+// GetUserIdFromJWT extracts the user ID from a JWT token.
+//
+// It returns an empty string and an error if the token is invalid or does not contain a user ID.
+// The signature is not checked, so only call it on tokens that already passed the auth middleware.
+func GetUserIdFromJWT(tokenString string) (string, error) {
+	claims, err := ParsePocketBaseJWT(tokenString)
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Id, nil
 }
 
 // VerifyJWTExpiration checks if the JWT token is still valid based on its expiration time.
 //
 // It returns true if the token is still valid, and an error if the token is invalid.
 func VerifyJWTExpiration(tokenString string) (bool, error) {
-	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &PocketBaseJWTPayload{})
+	claims, err := ParsePocketBaseJWT(tokenString)
 	if err != nil {
 		return false, err
 	}
 
-	if claims, ok := token.Claims.(*PocketBaseJWTPayload); ok {
-		return time.Now().Unix() < int64(claims.Exp), nil
-	}
-	return false, errors.New("invalid token or claims")
+	return time.Now().Unix() < int64(claims.Exp), nil
+}
+
+// HashToken returns the hex encoded SHA-256 digest of a token, used as a key so raw tokens are never stored.
+func HashToken(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
 }
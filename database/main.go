@@ -7,6 +7,8 @@ import (
 	"os"
 	"strings"
 
+	_ "alphalabz-database/migrations"
+
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
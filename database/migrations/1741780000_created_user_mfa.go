@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// user_mfa holds the TOTP secret and recovery codes of a user, one record per user.
+// Only the backend (superuser token) reads it, so every API rule stays locked.
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("user_mfa")
+		collection.Fields.Add(
+			&core.RelationField{Name: "user", CollectionId: users.Id, Required: true, MaxSelect: 1, CascadeDelete: true},
+			&core.TextField{Name: "secret", Hidden: true},
+			&core.BoolField{Name: "enabled"},
+			&core.JSONField{Name: "recovery_codes", Hidden: true},
+			&core.AutodateField{Name: "created", OnCreate: true},
+			&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+		)
+		collection.AddIndex("idx_user_mfa_user", true, "`user`", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("user_mfa")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// api_tokens holds the personal access tokens of the users, only the SHA-256 hash of each token is stored.
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("api_tokens")
+		collection.Fields.Add(
+			&core.RelationField{Name: "user", CollectionId: users.Id, Required: true, MaxSelect: 1, CascadeDelete: true},
+			&core.TextField{Name: "name", Required: true},
+			&core.TextField{Name: "token_hash", Required: true, Hidden: true},
+			&core.JSONField{Name: "scopes"},
+			&core.DateField{Name: "expires"},
+			&core.AutodateField{Name: "created", OnCreate: true},
+			&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+		)
+		collection.AddIndex("idx_api_tokens_token_hash", true, "`token_hash`", "")
+		collection.AddIndex("idx_api_tokens_user", false, "`user`", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("api_tokens")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// sessions holds the logins of the users, only the SHA-256 hash of each login token is stored.
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("sessions")
+		collection.Fields.Add(
+			&core.RelationField{Name: "user", CollectionId: users.Id, Required: true, MaxSelect: 1, CascadeDelete: true},
+			&core.TextField{Name: "token_hash", Required: true, Hidden: true},
+			&core.TextField{Name: "ip"},
+			&core.TextField{Name: "user_agent"},
+			&core.DateField{Name: "expires"},
+			&core.AutodateField{Name: "created", OnCreate: true},
+			&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+		)
+		collection.AddIndex("idx_sessions_token_hash", true, "`token_hash`", "")
+		collection.AddIndex("idx_sessions_user", false, "`user`", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("sessions")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// invitations holds the invites sent to future users, single and bulk.
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+		roles, err := app.FindCollectionByNameOrId("roles")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("invitations")
+		collection.Fields.Add(
+			&core.RelationField{Name: "inviter", CollectionId: users.Id, MaxSelect: 1, CascadeDelete: true},
+			&core.EmailField{Name: "email", Required: true},
+			&core.TextField{Name: "name"},
+			&core.TextField{Name: "group"},
+			&core.RelationField{Name: "role", CollectionId: roles.Id, MaxSelect: 1},
+			&core.SelectField{Name: "status", Required: true, MaxSelect: 1, Values: []string{"pending", "accepted", "revoked"}},
+			&core.DateField{Name: "expires"},
+			&core.DateField{Name: "last_sent"},
+			&core.AutodateField{Name: "created", OnCreate: true},
+			&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+		)
+		collection.AddIndex("idx_invitations_email", false, "`email`", "")
+		collection.AddIndex("idx_invitations_inviter", false, "`inviter`", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("invitations")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// suspended users keep their data but cannot sign in.
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		status, ok := users.Fields.GetByName("status").(*core.SelectField)
+		if !ok {
+			return errors.New("the users collection has no status select field")
+		}
+		if !slices.Contains(status.Values, "suspended") {
+			status.Values = append(status.Values, "suspended")
+		}
+
+		return app.Save(users)
+	}, func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		status, ok := users.Fields.GetByName("status").(*core.SelectField)
+		if !ok {
+			return nil
+		}
+		status.Values = slices.DeleteFunc(status.Values, func(value string) bool { return value == "suspended" })
+
+		return app.Save(users)
+	})
+}
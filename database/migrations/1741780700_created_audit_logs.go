@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// audit_logs is the audit trail of role changes and impersonations, and sessions.impersonator marks
+// the sessions an admin opened as another user.
+// The audit relations do not cascade: deleting a user keeps their entries, with the relation emptied.
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("audit_logs")
+		collection.Fields.Add(
+			&core.RelationField{Name: "actor", CollectionId: users.Id, MaxSelect: 1},
+			&core.RelationField{Name: "user", CollectionId: users.Id, MaxSelect: 1},
+			&core.TextField{Name: "action", Required: true},
+			&core.TextField{Name: "method"},
+			&core.TextField{Name: "path"},
+			&core.NumberField{Name: "status", OnlyInt: true},
+			&core.TextField{Name: "ip"},
+			&core.TextField{Name: "details"},
+			&core.AutodateField{Name: "created", OnCreate: true},
+		)
+		collection.AddIndex("idx_audit_logs_actor", false, "`actor`", "")
+		collection.AddIndex("idx_audit_logs_user", false, "`user`", "")
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		sessions, err := app.FindCollectionByNameOrId("sessions")
+		if err != nil {
+			return err
+		}
+		sessions.Fields.Add(&core.RelationField{Name: "impersonator", CollectionId: users.Id, MaxSelect: 1, CascadeDelete: true})
+
+		return app.Save(sessions)
+	}, func(app core.App) error {
+		sessions, err := app.FindCollectionByNameOrId("sessions")
+		if err != nil {
+			return err
+		}
+		sessions.Fields.RemoveByName("impersonator")
+		if err := app.Save(sessions); err != nil {
+			return err
+		}
+
+		collection, err := app.FindCollectionByNameOrId("audit_logs")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// parent is the role a role inherits its permissions from.
+func init() {
+	m.Register(func(app core.App) error {
+		roles, err := app.FindCollectionByNameOrId("roles")
+		if err != nil {
+			return err
+		}
+
+		roles.Fields.Add(&core.RelationField{Name: "parent", CollectionId: roles.Id, MaxSelect: 1})
+
+		return app.Save(roles)
+	}, func(app core.App) error {
+		roles, err := app.FindCollectionByNameOrId("roles")
+		if err != nil {
+			return err
+		}
+
+		roles.Fields.RemoveByName("parent")
+
+		return app.Save(roles)
+	})
+}